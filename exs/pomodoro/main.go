@@ -4,7 +4,6 @@ import (
     . "lib/std"
     "lib/fmt"
     "lib/doc"
-    "lib/math"
 )
 
 const (
@@ -47,25 +46,25 @@ func trailingZeros(s string, max int) string {
     return s
 }
 
-func (timer Timer) String() string {
+func (timer *Timer) String() string {
     time := timer.time
-    mins := math.FakeFloor(time / 60);
+    mins := time / 60
     secs := time - (mins * 60);
     return trailingZeros(Stringify(mins), 2) + ":" +
            trailingZeros(Stringify(secs), 2)
 }
 
-func (timer Timer) Init() {
+func (timer *Timer) Init() {
     timer.Reset()
 }
 
-func (timer Timer) Reset() {
+func (timer *Timer) Reset() {
     timer.Pause()
     timer.SetTime()
     timer.elem.Set("innerText", timer.String())
 }
 
-func (timer Timer) Start() {
+func (timer *Timer) Start() {
     if !timer.running {
         timer.running = true
         timer.interval = SetInterval(func() {
@@ -74,35 +73,31 @@ func (timer Timer) Start() {
     }
 }
 
-func (timer Timer) Pause() {
+func (timer *Timer) Pause() {
     timer.running = false
     ClearInterval(timer.interval)
 }
 
-func (timer Timer) WhenOver(f func ()) {
+func (timer *Timer) WhenOver(f func ()) {
     timer.whenOver = f
 }
 
-func (timer Timer) SetTime() {
+func (timer *Timer) SetTime() {
     switch (timer.mode) {
         case ModeSession:
             timer.time = timer.defaultSession
-            break
         case ModeBreak:
             timer.time = timer.defaultBreak
-            break
         case ModeLongBreak:
             timer.time = timer.defaultLongBreak
-            break
-        default: {}
     }
 }
 
-func (timer Timer) SetMode(mode int) {
+func (timer *Timer) SetMode(mode int) {
     timer.mode = mode
 }
 
-func (timer Timer) GetModeString() string {
+func (timer *Timer) GetModeString() string {
     switch (timer.mode) {
         case ModeSession: return "session"
         case ModeBreak: return "break"
@@ -111,7 +106,7 @@ func (timer Timer) GetModeString() string {
     }
 }
 
-func (timer Timer) Update() {
+func (timer *Timer) Update() {
     if timer.time > 0 {
         timer.time -= 1
     } else {
@@ -132,11 +127,11 @@ func (timer Timer) Update() {
     timer.elem.Set("innerText", timer.String())
 }
 
-func Button(text string, onclick string) doc.HTMLElement {
+func Button(text string, onclick func()) doc.HTMLElement {
     e := doc.CreateElement("button")
     e.Set("innerText", text)
     e.SetAttr("class", "timer__play_button")
-    e.SetAttr("onclick", onclick)
+    e.AddEventListener("click", onclick)
     return e
 }
 
@@ -155,7 +150,7 @@ func main() {
     root := root()
     container := timerContainer()
     title := doc.CreateElement("p")
-    timer := Timer{
+    timer := &Timer{
         time: 0,
         mode: ModeSession,
         elem: doc.CreateElement("p"),
@@ -171,9 +166,9 @@ func main() {
     buttons := doc.CreateElement("div")
     buttons.SetAttr("class", "timer__buttons")
 
-    buttons.AppendChild(Button("start", "timer.Start()"))
-    buttons.AppendChild(Button("pause", "timer.Pause()"))
-    buttons.AppendChild(Button("reset", "timer.Reset()"))
+    buttons.AppendChild(Button("start", func() { timer.Start() }))
+    buttons.AppendChild(Button("pause", func() { timer.Pause() }))
+    buttons.AppendChild(Button("reset", func() { timer.Reset() }))
 
     container.AppendChild(title)
     container.AppendChild(timer.elem)
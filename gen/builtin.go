@@ -0,0 +1,87 @@
+package gen
+
+import (
+    "go/ast"
+    "go/types"
+)
+
+// GenBuiltinCall generates a call to one of the predeclared functions
+// of go, the depth is already increased by GenCall.
+func (gen *Gen) GenBuiltinCall(builtin *types.Builtin, expr *ast.CallExpr) string {
+    if value := gen.Info.Types[expr].Value; value != nil {
+        // len and cap of arrays are constants
+        return value.ExactString()
+    }
+    switch builtin.Name() {
+        case "make": {
+            t := gen.Info.TypeOf(expr.Args[0])
+            switch u := CoreType(t).(type) {
+                case *types.Map: return "$makeMap(" + gen.MapHash(u.Key()) + ",[])"
+                case *types.Slice: return gen.GenMakeSlice(expr)
+                case *types.Chan: {
+                    if len(expr.Args) > 1 {
+                        return "$makeChan(" + gen.GenExpr(expr.Args[1]) + ")"
+                    }
+                    return "$makeChan(0)"
+                }
+                default: {}
+            }
+        }
+        case "len": {
+            arg := expr.Args[0]
+            if gen.IsMap(arg) {
+                return "$mapLen(" + gen.GenExpr(arg) + ")"
+            } else if gen.IsSlice(arg) {
+                return "$sliceLen(" + gen.GenExpr(arg) + ")"
+            } else if gen.IsChan(arg) {
+                return "$chanLen(" + gen.GenExpr(arg) + ")"
+            }
+            return gen.GenExpr(arg) + ".length"
+        }
+        case "cap": {
+            if gen.IsChan(expr.Args[0]) {
+                return "$chanCap(" + gen.GenExpr(expr.Args[0]) + ")"
+            }
+            return "$sliceCap(" + gen.GenExpr(expr.Args[0]) + ")"
+        }
+        case "close": {
+            return "$close(" + gen.GenExpr(expr.Args[0]) + ")"
+        }
+        case "append": {
+            return gen.GenAppend(expr)
+        }
+        case "copy": {
+            if gen.IsString(expr.Args[1]) {
+                return "$copy(" + gen.GenExpr(expr.Args[0]) + ",$stringToBytes(" + gen.GenExpr(expr.Args[1]) + "))"
+            }
+            return "$copy(" + gen.GenArgs(expr.Args, nil) + ")"
+        }
+        case "new": {
+            t := gen.Info.TypeOf(expr.Args[0])
+            if gen.IsStruct(t) || gen.IsJsType(t) {
+                return gen.ZeroValue(t)
+            }
+            return "$newPtr(" + gen.ZeroValue(t) + ")"
+        }
+        case "panic": {
+            return "$panic(" + gen.GenValue(expr.Args[0], types.NewInterfaceType(nil, nil)) + ")"
+        }
+        case "complex", "real", "imag": {
+            return gen.Errorf(expr, "complex numbers are not supported")
+        }
+        case "recover": {
+            if len(gen.funcs) == 0 {
+                // a package variable
+                return "$recover(null)"
+            }
+            return "$recover($deferPanic)"
+        }
+        case "delete": {
+            m := gen.MapType(expr.Args[0])
+            return "$mapDelete(" + gen.GenArgs(expr.Args, []types.Type{nil, m.Key()}) + ")"
+        }
+        default: {}
+    }
+    // print, println, clear, min and max
+    return gen.Errorf(expr, "%s is not supported", builtin.Name())
+}
@@ -0,0 +1,40 @@
+package gen
+
+import (
+    "strconv"
+    "strings"
+    "go/types"
+    "go/constant"
+)
+
+// Constants are evaluated by the type checker, every constant
+// expression is generated as the literal of its value so `iota`, the
+// implicit repetition of a const block and the untyped arithmetic are
+// already resolved. Constant declarations generate nothing.
+
+// GenConstant returns the js literal of the value of a constant
+// expression converted to its type.
+func (gen *Gen) GenConstant(tv types.TypeAndValue) string {
+    var out string
+    switch {
+        case tv.Value.Kind() == constant.Bool: out = tv.Value.ExactString()
+        case tv.Value.Kind() == constant.String: out = JsString(constant.StringVal(tv.Value))
+        case IsBigInt(tv.Type): out = gen.GenBigIntConstant(tv)
+        case IsInteger(types.Default(tv.Type)): out = tv.Value.ExactString()
+        default: {
+            f, _ := constant.Float64Val(constant.ToFloat(tv.Value))
+            if basic := basicOf(tv.Type); basic != nil && basic.Kind() == types.Float32 {
+                f = float64(float32(f))
+            }
+            out = strconv.FormatFloat(f, 'g', -1, 64)
+        }
+    }
+    if strings.HasPrefix(out, "-") {
+        // x - -1 must not become x--1
+        out = "(" + out + ")"
+    }
+    if tp, ok := tv.Type.(*types.TypeParam); ok && tv.Value.Kind() != constant.String && tv.Value.Kind() != constant.Bool {
+        return TypeParamDesc(tp) + ".conv(" + out + ")"
+    }
+    return out
+}
@@ -0,0 +1,210 @@
+package gen
+
+import (
+    "go/ast"
+    "strings"
+    "go/types"
+)
+
+// Deferred calls are pushed on the `$defers` stack of the function and
+// run by the runtime when the body exits, see runtime.js for panics.
+
+// HasDefer reports if a function body has deferred calls, the ones of
+// nested function literals belong to those functions.
+func HasDefer(body *ast.BlockStmt) bool {
+    found := false
+    ast.Inspect(body, func(node ast.Node) bool {
+        switch node.(type) {
+            case *ast.DeferStmt: found = true
+            case *ast.FuncLit: return false
+            default: {}
+        }
+        return !found
+    })
+    return found
+}
+
+// Recovers reports if a function body calls `recover`, only the calls
+// of the function that is deferred can stop a panic so the function
+// takes the panic as a last argument that only deferred calls pass.
+func (gen *Gen) Recovers(body *ast.BlockStmt) bool {
+    if body == nil {
+        return false
+    }
+    found := false
+    ast.Inspect(body, func(node ast.Node) bool {
+        switch e := node.(type) {
+            case *ast.FuncLit: return false
+            case *ast.CallExpr: {
+                builtin, ok := gen.ObjectOf(e.Fun).(*types.Builtin)
+                found = found || ok && builtin.Name() == "recover"
+            }
+            default: {}
+        }
+        return !found
+    })
+    return found
+}
+
+// GenDeferBody generates the body of a function with deferred calls,
+// the deferred calls run when the body returns or panics.
+func (gen *Gen) GenDeferBody(body *ast.BlockStmt) string {
+    fun := gen.CurrentFunc()
+
+    var out string
+    out += "const $defers=[];let $panicked=null;"
+    out += "try{" + gen.GenBlockStmt(body) + "}"
+    out += "catch($e){$panicked=$toPanic($e);}"
+    out += "finally{"
+    if fun.Async {
+        out += "$panicked=await $runDefersAsync($defers,$panicked);"
+    } else {
+        out += "$panicked=$runDefers($defers,$panicked);"
+    }
+    out += "if ($panicked!==null&&!$panicked.recovered){throw $panicked;}"
+
+    names := gen.NamedResults(fun.Type)
+    if len(names) == 1 {
+        out += "return " + names[0] + ";"
+    } else if len(names) > 1 {
+        out += "return [" + strings.Join(names, ",") + "];"
+    } else if results := gen.ResultTypes(fun.Type); len(results) > 0 {
+        // a recovered panic returns the zero values
+        var zeros []string
+        for _, t := range results {
+            zeros = append(zeros, gen.ZeroValue(t))
+        }
+        out += "if ($panicked!==null){return "
+        if len(zeros) == 1 {
+            out += zeros[0]
+        } else {
+            out += "[" + strings.Join(zeros, ",") + "]"
+        }
+        out += ";}"
+    }
+
+    out += "}"
+    return out
+}
+
+// GenDeferReturn generates a return of a function with deferred calls
+// and named results, the results are set and returned after running
+// the deferred calls.
+func (gen *Gen) GenDeferReturn(stmt *ast.ReturnStmt) string {
+    fun := gen.CurrentFunc()
+    if len(stmt.Results) == 0 {
+        return "return;"
+    }
+
+    var names []ast.Expr
+    for _, field := range fun.Type.Results.List {
+        for _, name := range field.Names {
+            names = append(names, name)
+        }
+    }
+
+    var out string
+
+    gen.AddDepth()
+
+    if len(names) == 1 {
+        if !isBlank(names[0]) {
+            out += gen.GenExpr(names[0]) + "="
+        }
+        out += gen.GenValue(stmt.Results[0], gen.Info.TypeOf(names[0])) + ";"
+    } else if len(stmt.Results) == 1 {
+        // return f() where f returns multiple values
+        out += gen.GenTuple(names) + "=" + gen.GenExpr(stmt.Results[0]) + ";"
+    } else {
+        out += gen.GenTuple(names) + "=[" + gen.GenValues(stmt.Results, gen.ResultTypes(fun.Type)) + "];"
+    }
+
+    gen.RemDepth()
+
+    out += "return;"
+    return out
+}
+
+func (gen *Gen) GenDeferStmt(stmt *ast.DeferStmt) string {
+    return "$defers.push(" + gen.GenDeferredCall(stmt.Call) + ");"
+}
+
+// GenDeferredCall generates a js function that makes a call later, the
+// function value, the receiver and the arguments are evaluated now.
+func (gen *Gen) GenDeferredCall(call *ast.CallExpr) string {
+    var operands []ast.Expr
+    var values []string
+
+    gen.AddDepth()
+
+    switch fun := unparen(call.Fun).(type) {
+        case *ast.SelectorExpr: {
+            if sel := gen.Info.Selections[fun]; sel != nil && sel.Kind() == types.MethodVal {
+                operands = append(operands, fun.X)
+                if gen.IsRecvParam(fun) {
+                    values = append(values, gen.GenRecvArg(fun))
+                } else {
+                    values = append(values, gen.GenMethodRecv(fun))
+                }
+            }
+        }
+        case *ast.Ident: {
+            if _, isVar := gen.ObjectOf(fun).(*types.Var); isVar {
+                // a function variable
+                operands = append(operands, fun)
+                values = append(values, gen.GenExpr(fun))
+            }
+        }
+        case *ast.FuncLit: {}
+        case *ast.IndexExpr, *ast.IndexListExpr: {
+            if gen.FuncTypeArgs(fun) == nil {
+                // a function value of a slice or map
+                operands = append(operands, fun)
+                values = append(values, gen.GenExpr(fun))
+            }
+        }
+        default: {
+            operands = append(operands, fun)
+            values = append(values, gen.GenExpr(fun))
+        }
+    }
+    for _, arg := range call.Args {
+        operands = append(operands, arg)
+        values = append(values, gen.GenValue(arg, nil))
+    }
+
+    var params []string
+    for _, operand := range operands {
+        name := gen.Temp("a")
+        params = append(params, name)
+        gen.Evaluated(operand, name)
+    }
+
+    panicArg := gen.Temp("panic")
+    gen.panicArg = panicArg
+    body := gen.GenCall(call)
+
+    for _, operand := range operands {
+        delete(gen.evaluated, operand)
+    }
+
+    gen.RemDepth()
+
+    fun := "(" + panicArg + ")=>{" + body + "}"
+    if gen.IsAsyncCall(call) {
+        fun = "async " + fun
+    }
+    if len(operands) == 0 {
+        return fun
+    }
+    return "((" + strings.Join(params, ",") + ")=>" + fun + ")(" + strings.Join(values, ",") + ")"
+}
+
+// Evaluated makes expr to be generated as the js expression name until
+// it is deleted from gen.evaluated.
+func (gen *Gen) Evaluated(expr ast.Expr, name string) {
+    if gen.evaluated == nil {
+        gen.evaluated = map[ast.Expr]string{}
+    }
+    gen.evaluated[expr] = name
+}
@@ -0,0 +1,91 @@
+package gen
+
+import (
+    "go/ast"
+    "go/types"
+)
+
+// Embedded fields are fields named like their type. A promoted field
+// is selected through the embedded fields of its path and the promoted
+// methods are forwarded by methods of the outer struct, so values of it
+// implement the interfaces of the embedded types.
+
+// structOf returns the struct of a struct or pointer to struct type.
+func structOf(t types.Type) *types.Struct {
+    if ptr, ok := t.Underlying().(*types.Pointer); ok {
+        t = ptr.Elem()
+    }
+    st, _ := t.Underlying().(*types.Struct)
+    return st
+}
+
+// GenFieldOwner generates the struct that holds the field selected by
+// expr, the embedded fields that lead to a promoted field are selected.
+func (gen *Gen) GenFieldOwner(expr *ast.SelectorExpr) string {
+    sel := gen.Info.Selections[expr]
+    gen.AddDepth()
+    out := gen.GenExpr(expr.X)
+    gen.RemDepth()
+    t := sel.Recv()
+    for _, i := range sel.Index()[:len(sel.Index()) - 1] {
+        field := structOf(t).Field(i)
+        out += "." + field.Name()
+        t = field.Type()
+    }
+    return out
+}
+
+// IsParamMethod reports if the selected method is declared by a type
+// whose methods take their receiver as the first argument, promoted
+// methods are called through the struct that promotes them.
+func (gen *Gen) IsParamMethod(sel *types.Selection) bool {
+    if len(sel.Index()) > 1 {
+        return false
+    }
+    return gen.RecvIsParam(RecvType(MethodRecv(sel.Obj()).Type()))
+}
+
+// GenPromotedMethods generates the methods of a struct type that
+// forward the methods promoted by its embedded fields.
+func (gen *Gen) GenPromotedMethods(t *types.Named) string {
+    var out string
+    name := gen.TypeName(t)
+    mset := types.NewMethodSet(types.NewPointer(t))
+    for i := 0; i < mset.Len(); i++ {
+        sel := mset.At(i)
+        if len(sel.Index()) == 1 {
+            continue
+        }
+
+        var owner, parent string
+        var field *types.Var
+        owner = "this"
+        embedded := types.Type(t)
+        for _, j := range sel.Index()[:len(sel.Index()) - 1] {
+            field = structOf(embedded).Field(j)
+            parent, owner = owner, owner + "." + field.Name()
+            embedded = field.Type()
+        }
+
+        method := sel.Obj().(*types.Func)
+        targs := gen.GenTypeArgs(NamedTypeArgs(RecvType(field.Type())))
+        if IsInterface(field.Type()) {
+            targs = ""
+        }
+        call := owner + "." + method.Name() + "(" + joinArgs(targs, "...a") + ")"
+        if recv := MethodRecv(method).Type(); gen.RecvIsParam(RecvType(recv)) {
+            _, recvPtr := recv.(*types.Pointer)
+            _, fieldPtr := field.Type().(*types.Pointer)
+            switch {
+                case recvPtr && !fieldPtr: owner = "$fieldPtr(" + parent + ",\"" + field.Name() + "\")"
+                case !recvPtr && fieldPtr: owner = gen.CloneValue(owner + ".$get()", recv)
+                case !recvPtr: owner = gen.CloneValue(owner, recv)
+                default: {}
+            }
+            call = gen.GenMethodFunc(method, false, "") + "(" + joinArgs(targs, owner, "...a") + ")"
+        }
+        params := joinArgs(GenTypeParams(t.TypeParams()), "...a")
+        out += name + ".prototype." + method.Name() + "=function(" + params + "){return " + call + ";};"
+    }
+    return out
+}
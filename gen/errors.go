@@ -0,0 +1,30 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "reflect"
+    "go/token"
+)
+
+// Error is a diagnostic of a construct that cannot be generated.
+type Error struct {
+    Pos token.Position
+    Msg string
+}
+
+func (err Error) Error() string {
+    return fmt.Sprintf("%v: %s", err.Pos, err.Msg)
+}
+
+// Errorf records an error at the position of node, the generation
+// keeps going so every error of the package is reported at once. A
+// nil node, like the missing part of a statement, has no position.
+func (gen *Gen) Errorf(node ast.Node, format string, args ...interface{}) string {
+    err := Error{Msg: fmt.Sprintf(format, args...)}
+    if node != nil && !reflect.ValueOf(node).IsNil() {
+        err.Pos = gen.Fset.Position(node.Pos())
+    }
+    gen.Errors = append(gen.Errors, err)
+    return ""
+}
@@ -5,19 +5,109 @@ import (
     "go/ast"
     "strings"
     "reflect"
+    "go/token"
+    "go/types"
+    "go/constant"
     "golang.org/x/tools/go/packages"
 )
 
-type binds map[string]string
+type binds map[types.Object]string
 
 type Gen struct {
     Pkgs []*packages.Package
+    // Fset is the file set of Pkgs, it positions the errors.
+    Fset *token.FileSet
+    // Info:
+    //   Type information of every package in Pkgs, it is used to
+    //   resolve identifiers to the object they refer instead of
+    //   looking declarations up by name.
+    Info *types.Info
     Binds binds
+    // Errors:
+    //   The constructs that could not be generated, the output is not
+    //   valid if there is any error.
+    Errors []Error
     // depth:
     //   If depth is bigger than 0 we are inside a `(...)` or `[...]`
     //   otherwise we are inside a function this is usefull for knowing
     //   where to add the `;` symbol.
     depth int
+    // funcs:
+    //   Stack of the functions being generated, the top is the
+    //   function that owns the current `return` statement.
+    funcs []*Func
+    // temps:
+    //   Counter used to name the temporary variables of the generated
+    //   code so nested ones do not shadow each other.
+    temps int
+    // rtypes:
+    //   The types that need a runtime descriptor, see TypeDesc.
+    rtypes []types.Type
+    // evaluated:
+    //   Expressions whose value is already stored on a js variable,
+    //   see GenDeferStmt.
+    evaluated map[ast.Expr]string
+    // async:
+    //   The function bodies that can block, async functions and their
+    //   signatures are found by FindAsync.
+    async map[*ast.BlockStmt]bool
+    asyncSigs []*types.Signature
+    // targets:
+    //   Stack of the statements that break and continue can leave.
+    targets []*target
+    // gotos:
+    //   The labels targeted by goto, see GenGotoBlock.
+    gotos map[types.Object]*gotoLabel
+    // hoisted:
+    //   If it is not nil the variables declared by the statements
+    //   being generated are added to it instead of being declared.
+    hoisted *[]string
+    // SharedLoopVars:
+    //   The variables declared by a loop are shared by all of its
+    //   iterations like before go 1.22, by default each iteration
+    //   has its own variables.
+    SharedLoopVars bool
+    // loopVars:
+    //   The variables being declared belong to a loop, see LoopDecl.
+    loopVars bool
+    // label:
+    //   The go label of the next statement passed to GenTarget.
+    label string
+    // inits:
+    //   The names of the `init` functions of the package being
+    //   generated, see GenPkgInit.
+    inits []string
+    // panicArg:
+    //   The js name of the panic passed to the deferred call being
+    //   generated, see GenDeferredCall.
+    panicArg string
+    // addressed:
+    //   The variables whose address is taken, see FindAddressed.
+    addressed map[types.Object]bool
+    // ptrs:
+    //   The js names of the pointers declared with the addressed
+    //   variables, see GenPtrDecl.
+    ptrs map[types.Object]string
+    // jsTypes:
+    //   The `js-bind` comments of the types bound to js, see
+    //   FindJsTypes.
+    jsTypes map[types.Object]*ast.CommentGroup
+}
+
+// Func is a function being generated.
+type Func struct {
+    Type *ast.FuncType
+    // Defers:
+    //   The function has deferred calls, its named results are set
+    //   before returning so the deferred calls can change them.
+    Defers bool
+    // Async:
+    //   The function can block, see FindAsync.
+    Async bool
+    // Recovers:
+    //   The function calls `recover`, it takes the panic it can
+    //   recover as its last argument, see Recovers.
+    Recovers bool
 }
 
 func (gen *Gen) AddDepth() {
@@ -32,6 +122,29 @@ func (gen *Gen) AddSemicolon() bool {
     return gen.depth == 0
 }
 
+// Temp returns a new name for a temporary js variable.
+func (gen *Gen) Temp(name string) string {
+    gen.temps++
+    return fmt.Sprintf("$%s%d", name, gen.temps)
+}
+
+func (gen *Gen) PushFunc(fun *ast.FuncType, body *ast.BlockStmt) {
+    gen.funcs = append(gen.funcs, &Func{
+        Type: fun,
+        Defers: HasDefer(body),
+        Async: gen.async[body],
+        Recovers: gen.Recovers(body),
+    })
+}
+
+func (gen *Gen) PopFunc() {
+    gen.funcs = gen.funcs[:len(gen.funcs)-1]
+}
+
+func (gen *Gen) CurrentFunc() *Func {
+    return gen.funcs[len(gen.funcs)-1]
+}
+
 func (gen *Gen) Decls() []ast.Decl {
     var out []ast.Decl
     for _, pkg := range gen.Pkgs {
@@ -44,30 +157,29 @@ func (gen *Gen) Decls() []ast.Decl {
     return out
 }
 
-func (gen *Gen) LookupType(name string) *ast.TypeSpec {
-    for _, decl := range gen.Decls() {
-        switch e := decl.(type) {
-            case *ast.GenDecl: {
-                if e.Tok.String() == "type" {
-                    for _, type_ := range e.Specs {
-                        t, _ := type_.(*ast.TypeSpec)
-                        if t.Name.Name == name {
-                            return t
-                        }
-                    }
-                }
-            }
-            default: {}
-        }
+func (gen *Gen) ObjectOf(expr ast.Expr) types.Object {
+    switch e := expr.(type) {
+        case *ast.Ident: return gen.Info.ObjectOf(e)
+        case *ast.SelectorExpr: return gen.Info.ObjectOf(e.Sel)
+        case *ast.ParenExpr: return gen.ObjectOf(e.X)
+        case *ast.IndexExpr: return gen.ObjectOf(e.X)
+        case *ast.IndexListExpr: return gen.ObjectOf(e.X)
+        default: return nil
     }
-    return nil
 }
 
-func (gen *Gen) LookupFunc(name string) *ast.FuncDecl {
+func (gen *Gen) LookupFunc(obj types.Object) *ast.FuncDecl {
+    if obj == nil {
+        return nil
+    }
+    if fun, ok := obj.(*types.Func); ok {
+        // the methods of instances of generic types
+        obj = OriginFunc(fun)
+    }
     for _, decl := range gen.Decls() {
         switch e := decl.(type) {
             case *ast.FuncDecl: {
-                if e.Name.Name == name {
+                if gen.Info.Defs[e.Name] == obj {
                     return e
                 }
             }
@@ -87,27 +199,40 @@ func (gen *Gen) GenPkgs() string {
 
 func (gen *Gen) GenPkg(pkg *packages.Package) string {
     var out string
+    gen.FindAsync(pkg.Syntax)
+    gen.FindGotos(pkg.Syntax)
+    gen.FindAddressed(pkg.Syntax)
     for _, file := range pkg.Syntax {
         out += gen.GenFile(file)
     }
-    return out
+    out += gen.GenPkgInit(pkg.Syntax)
+    // the descriptors are used by the code of the package
+    return gen.GenTypeDescs() + out
 }
 
 func (gen *Gen) GenFile(file *ast.File) string {
     var out string
     for _, decl := range file.Decls {
-        switch e := decl.(type) {
-            case *ast.FuncDecl: {
-                out += gen.GenFuncDecl(e)
-            }
-            case *ast.GenDecl: {
-                if e.Tok.String() == "import" {
-                    continue
-                }
-                out += gen.GenGenDecl(e)
+        out += gen.GenDecl(decl)
+    }
+    return out
+}
+
+// GenDecl generates a top level declaration.
+func (gen *Gen) GenDecl(decl ast.Decl) string {
+    var out string
+    switch e := decl.(type) {
+        case *ast.FuncDecl: {
+            out += gen.GenFuncDecl(e)
+        }
+        case *ast.GenDecl: {
+            switch e.Tok {
+                case token.IMPORT: {}
+                case token.VAR: out += gen.GenPkgVars(e)
+                default: out += gen.GenGenDecl(e)
             }
-            default: {}
         }
+        default: {}
     }
     return out
 }
@@ -116,34 +241,67 @@ func (gen *Gen) GenFuncDecl(fun *ast.FuncDecl) string {
     var out string
 
     if fun.Recv != nil && len(fun.Recv.List) != 1 {
-        panic(fmt.Sprintf("GenFuncDecl cannot generate function '%s'", fun.Name.Name))
+        return gen.Errorf(fun, "cannot generate function '%s'", fun.Name.Name)
     }
 
+    var recv *types.Var
+    var recvParam string
     if fun.Recv != nil {
-        out += gen.GenExpr(fun.Recv.List[0].Type)
-        out += ".prototype."
+        recv = gen.Info.Defs[fun.Name].Type().(*types.Signature).Recv()
+        out += gen.TypeName(RecvType(recv.Type()))
+        if gen.RecvIsParam(RecvType(recv.Type())) {
+            // the receiver is the first argument, see named.go
+            recvParam = gen.Temp("recv")
+            if recv.Name() != "" && recv.Name() != "_" {
+                recvParam = gen.GenIdent(fun.Recv.List[0].Names[0])
+            }
+            out += "."
+        } else {
+            out += ".prototype."
+        }
         out += fun.Name.Name
         out += "="
     }
 
+    if gen.async[fun.Body] {
+        out += "async "
+    }
     out += "function "
 
-    if fun.Recv == nil {
-        out += fun.Name.Name
+    if fun.Recv == nil && fun.Name.Name == "init" {
+        out += gen.GenInitFunc(fun)
+    } else if fun.Recv == nil {
+        out += JsName(fun.Name.Name)
     }
 
+    sig := gen.Info.Defs[fun.Name].Type().(*types.Signature)
+    tparams := GenTypeParams(sig.TypeParams())
+    if recv != nil {
+        tparams = GenTypeParams(sig.RecvTypeParams())
+    }
     out += "("
-    out += gen.GenFields(fun.Type.Params)
+    var recoverParam string
+    if gen.Recovers(fun.Body) {
+        recoverParam = "$deferPanic"
+    }
+    out += joinArgs(tparams, recvParam, gen.GenFields(fun.Type.Params), recoverParam)
     out += ")"
     out += "{"
 
-    if fun.Recv != nil {
-        gen.Binds[gen.GenIdent(fun.Recv.List[0].Names[0])] = "this"
+    if recvParam == "" && recv != nil && recv.Name() != "" && recv.Name() != "_" {
+        if _, isPtr := recv.Type().(*types.Pointer); isPtr {
+            gen.Binds[recv] = "this"
+        } else {
+            // value receivers get a copy of the value
+            out += "let " + JsName(recv.Name()) + "=" + gen.CloneValue("this", recv.Type()) + ";"
+        }
     }
 
-    out += gen.GenBlockStmt(fun.Body)
+    gen.PushFunc(fun.Type, fun.Body)
+    out += gen.GenFuncBody(fun.Body)
+    gen.PopFunc()
 
-    gen.Binds = map[string]string{}
+    gen.Binds = binds{}
 
     out += "}"
 
@@ -156,32 +314,66 @@ func (gen *Gen) GenFuncDecl(fun *ast.FuncDecl) string {
 func (gen *Gen) GenStmt(stmt ast.Stmt) string {
     switch t := stmt.(type) {
         case *ast.IfStmt: return gen.GenIfStmt(t)
-        case *ast.ForStmt: return gen.GenForStmt(t)
+        case *ast.ForStmt: {
+            return gen.GenTarget(true, func() string { return gen.GenForStmt(t) })
+        }
         case *ast.DeclStmt: return gen.GenDeclStmt(t)
         case *ast.ExprStmt: return gen.GenExprStmt(t)
         case *ast.ReturnStmt: return gen.GenReturnStmt(t)
         case *ast.AssignStmt: return gen.GenAssignStmt(t)
         case *ast.IncDecStmt: return gen.GenIncDecStmt(t)
-        case *ast.SwitchStmt: return gen.GenSwitchStmt(t)
-        case *ast.CaseClause: return gen.GenCaseClause(t)
+        case *ast.SwitchStmt: {
+            return gen.GenTarget(false, func() string { return gen.GenSwitchStmt(t) })
+        }
+        case *ast.TypeSwitchStmt: {
+            return gen.GenTarget(false, func() string { return gen.GenTypeSwitchStmt(t) })
+        }
         case *ast.BranchStmt: return gen.GenBranchStmt(t)
-        case *ast.RangeStmt: return gen.GenRangeStmt(t)
-        case *ast.BlockStmt: return gen.GenBlockStmt(t)
-        case nil: return ""
+        case *ast.RangeStmt: {
+            return gen.GenTarget(true, func() string { return gen.GenRangeStmt(t) })
+        }
+        case *ast.BlockStmt: return "{" + gen.GenBlockStmt(t) + "}"
+        case *ast.DeferStmt: return gen.GenDeferStmt(t)
+        case *ast.GoStmt: return gen.GenGoStmt(t)
+        case *ast.SendStmt: return gen.GenSendStmt(t)
+        case *ast.SelectStmt: {
+            return gen.GenTarget(false, func() string { return gen.GenSelectStmt(t) })
+        }
+        case *ast.LabeledStmt: return gen.GenLabeledStmt(t)
+        case *ast.EmptyStmt, nil: return ""
         default: {
-            panic(fmt.Sprintf("GenStmt not implemented for (%+v)", reflect.TypeOf(stmt)))
+            return gen.Errorf(stmt, "statement not supported (%v)", reflect.TypeOf(stmt))
         }
     }
 }
 
 func (gen *Gen) GenReturnStmt(stmt *ast.ReturnStmt) string {
+    if rf := gen.RangeFunc(); rf != nil {
+        return gen.GenRangeFuncReturn(stmt, rf)
+    }
+    fun := gen.CurrentFunc()
+    if fun.Defers && len(gen.NamedResults(fun.Type)) > 0 {
+        return gen.GenDeferReturn(stmt)
+    }
+
     var out string
     out += "return "
 
     gen.AddDepth()
 
-    for _, expr := range stmt.Results {
-        out += gen.GenExpr(expr)
+    results := stmt.Results
+    if len(results) == 0 {
+        // naked return, the named results are returned
+        names := gen.NamedResults(fun.Type)
+        if len(names) == 1 {
+            out += names[0]
+        } else if len(names) > 1 {
+            out += "[" + strings.Join(names, ",") + "]"
+        }
+    } else if len(results) == 1 {
+        out += gen.GenValue(results[0], gen.ResultTypes(fun.Type)[0])
+    } else {
+        out += "[" + gen.GenValues(results, gen.ResultTypes(fun.Type)) + "]"
     }
 
     gen.RemDepth()
@@ -191,6 +383,17 @@ func (gen *Gen) GenReturnStmt(stmt *ast.ReturnStmt) string {
 }
 
 func (gen *Gen) GenBlockStmt(expr *ast.BlockStmt) string {
+    // only the declarations of the list of a goto block are hoisted
+    hoisted := gen.hoisted
+    gen.hoisted = nil
+    defer func() { gen.hoisted = hoisted }()
+
+    for _, stmt := range expr.List {
+        if gen.IsGotoTarget(stmt) {
+            return gen.GenGotoBlock(expr.List)
+        }
+    }
+
     var out string
     for _, stmt := range expr.List {
         out += gen.GenStmt(stmt)
@@ -199,6 +402,12 @@ func (gen *Gen) GenBlockStmt(expr *ast.BlockStmt) string {
 }
 
 func (gen *Gen) GenIfStmt(expr *ast.IfStmt) string {
+    return gen.GenScoped(expr.Init, func() string {
+        return gen.GenIf(expr)
+    })
+}
+
+func (gen *Gen) GenIf(expr *ast.IfStmt) string {
     gen.AddDepth()
 
     var cond string = gen.GenExpr(expr.Cond)
@@ -208,7 +417,9 @@ func (gen *Gen) GenIfStmt(expr *ast.IfStmt) string {
     var body string = gen.GenBlockStmt(expr.Body)
     var elsi string = ""
 
-    if expr.Else != nil {
+    if block, ok := expr.Else.(*ast.BlockStmt); ok {
+        elsi += "else{" + gen.GenBlockStmt(block) + "}"
+    } else if expr.Else != nil {
         elsi += "else{" + gen.GenStmt(expr.Else) + "}"
     }
 
@@ -218,51 +429,151 @@ func (gen *Gen) GenIfStmt(expr *ast.IfStmt) string {
 func (gen *Gen) GenForStmt(expr *ast.ForStmt) string {
     gen.AddDepth()
 
-    var init string = gen.GenStmt(expr.Init)
-    var cond string = gen.GenExpr(expr.Cond)
+    var init string = gen.GenLoopInit(expr.Init)
+    var cond string
+    if expr.Cond != nil {
+        cond = gen.GenExpr(expr.Cond)
+    }
     var post string = gen.GenStmt(expr.Post)
+    if copies := gen.GenIterationCopies(expr.Init); copies != nil {
+        if post != "" {
+            copies = append(copies, post)
+        }
+        post = strings.Join(copies, ",")
+    }
 
     gen.RemDepth()
     
     var body string = gen.GenBlockStmt(expr.Body)
+    if assign, ok := expr.Init.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE {
+        // the pointers to the variables of each iteration
+        body = gen.GenPtrDecls(assign.Lhs) + body
+    }
 
     return "for (" + init + ";" + cond + ";" + post + ")" + "{" + body + "}"
 }
 
 func (gen *Gen) GenIncDecStmt(expr *ast.IncDecStmt) string {
-    out := gen.GenExpr(expr.X) + expr.Tok.String()
+    var out string
+    t := gen.Info.TypeOf(expr.X)
+    if gen.IsStore(expr.X) || !IsPlainArith(token.ADD, t) {
+        op := token.ADD
+        if expr.Tok == token.DEC {
+            op = token.SUB
+        }
+        one := "1"
+        if IsBigInt(t) {
+            one = "1n"
+        } else if tp, ok := t.(*types.TypeParam); ok {
+            one = TypeParamDesc(tp) + ".conv(1)"
+        }
+        gen.AddDepth()
+        value := gen.GenArith(op, gen.GenExpr(expr.X), one, t, t)
+        if gen.IsStore(expr.X) {
+            out = gen.GenStore(expr.X, value)
+        } else {
+            out = gen.GenExpr(expr.X) + "=" + value
+        }
+        gen.RemDepth()
+    } else {
+        out = gen.GenExpr(expr.X) + expr.Tok.String()
+    }
     if gen.AddSemicolon() {
         out += ";"
     }
     return out
 }
 
-func (gen *Gen) GenCaseClause(expr *ast.CaseClause) string {
+// GenCaseClause generates a clause of a switch on the js value tag of
+// type t, a clause of a switch without tag compares with true. The
+// clauses break at the end unless they fall through.
+func (gen *Gen) GenCaseClause(expr *ast.CaseClause, tag string, t types.Type) string {
     out := ""
     if len(expr.List) < 1 {
-        out += "default"
-    } else {
-        out += "case " + gen.GenExpr(expr.List[0])
+        out += "default:"
     }
-    out += ":{"
-    for _, stmt := range expr.Body {
-        out += gen.GenStmt(stmt)
+
+    gen.AddDepth()
+
+    for _, e := range expr.List {
+        if t == nil {
+            out += "case " + gen.GenExpr(e) + ":"
+        } else {
+            out += "case " + gen.GenEqual(tag, t, gen.GenExpr(e), gen.Info.TypeOf(e)) + ":"
+        }
+    }
+
+    gen.RemDepth()
+
+    out += "{"
+    out += gen.GenBlockStmt(&ast.BlockStmt{List: expr.Body})
+    if n := len(expr.Body); n == 0 || !isTerminating(expr.Body[n-1]) {
+        out += "break;"
     }
     out += "}"
     return out
 }
 
+// isTerminating reports if the statement ends a case clause, the
+// implicit break of the clause is not needed.
+func isTerminating(stmt ast.Stmt) bool {
+    switch e := stmt.(type) {
+        case *ast.ReturnStmt: return true
+        case *ast.BranchStmt: return e.Tok != token.GOTO
+        default: return false
+    }
+}
+
 func (gen *Gen) GenBranchStmt(expr *ast.BranchStmt) string {
-    return expr.Tok.String()
+    switch {
+        case expr.Tok == token.FALLTHROUGH: {
+            // the clause does not break
+            return ""
+        }
+        case expr.Tok == token.GOTO: return gen.GenGoto(expr.Label)
+        default: return gen.GenBranch(expr)
+    }
 }
 
+// GenSwitchStmt generates a switch as a js switch on true, the case
+// expressions are comparisons with the tag evaluated once.
 func (gen *Gen) GenSwitchStmt(expr *ast.SwitchStmt) string {
-    tag := gen.GenExpr(expr.Tag)
-    body := gen.GenBlockStmt(expr.Body)
-    return "switch" + tag + "{" + body + "}"
+    return gen.GenScoped(expr.Init, func() string {
+        return gen.GenSwitch(expr)
+    })
+}
+
+func (gen *Gen) GenSwitch(expr *ast.SwitchStmt) string {
+    var out string
+
+    var tag string
+    var t types.Type
+    if expr.Tag != nil {
+        tag = gen.Temp("tag")
+        t = types.Default(gen.Info.TypeOf(expr.Tag))
+        gen.AddDepth()
+        out += "const " + tag + "=" + gen.GenExpr(expr.Tag) + ";"
+        gen.RemDepth()
+    }
+
+    out += "switch (true) {"
+    for _, stmt := range expr.Body.List {
+        out += gen.GenCaseClause(stmt.(*ast.CaseClause), tag, t)
+    }
+    out += "}"
+    return out
 }
 
 func (gen *Gen) GenExpr(expr ast.Expr) string {
+    if name, ok := gen.evaluated[expr]; ok {
+        return name
+    }
+    if IsComplex(gen.Info.TypeOf(expr)) {
+        return gen.Errorf(expr, "complex numbers are not supported")
+    }
+    if tv, ok := gen.Info.Types[expr]; ok && tv.Value != nil {
+        return gen.GenConstant(tv)
+    }
     switch e := expr.(type) {
         case *ast.Ident: return gen.GenIdent(e)
         case *ast.BasicLit: return gen.GenBasicLit(e)
@@ -275,30 +586,93 @@ func (gen *Gen) GenExpr(expr ast.Expr) string {
         case *ast.UnaryExpr: return gen.GenUnaryExpr(e)
         case *ast.ParenExpr: return gen.GenParenExpr(e)
         case *ast.FuncLit: return gen.GenFuncLit(e)
+        case *ast.IndexExpr: return gen.GenIndexExpr(e)
+        case *ast.IndexListExpr: return gen.GenFuncInstance(e)
+        case *ast.SliceExpr: return gen.GenSliceExpr(e)
+        case *ast.StarExpr: return gen.GenStarExpr(e)
+        case *ast.TypeAssertExpr: return gen.GenTypeAssertExpr(e)
         default: {
-            panic(fmt.Sprintf("GenExpr not implemented for (%+v)", reflect.TypeOf(expr)))
+            return gen.Errorf(expr, "expression not supported (%v)", reflect.TypeOf(expr))
         }
     }
 }
 
 func (gen *Gen) GenIdent(expr *ast.Ident) string {
-    if val, ok := gen.Binds[expr.Name]; ok {
+    obj := gen.Info.ObjectOf(expr)
+    if val, ok := gen.Binds[obj]; ok {
         return val
-    } else {
+    } else if gen.FuncTypeArgs(expr) != nil {
+        return gen.GenFuncInstance(expr)
+    } else if _, ok := obj.(*types.Nil); ok {
+        return "null"
+    } else if obj == nil || obj.Parent() == types.Universe {
         return expr.Name
+    } else if v, ok := obj.(*types.Var); ok && v.IsField() {
+        return expr.Name
+    } else if fun, ok := obj.(*types.Func); ok && fun.Type().(*types.Signature).Recv() != nil {
+        return expr.Name
+    } else {
+        return JsName(expr.Name)
     }
 }
 
 func (gen *Gen) GenBinaryExpr(expr *ast.BinaryExpr) string {
-    return gen.GenExpr(expr.X) + expr.Op.String() + gen.GenExpr(expr.Y)
+    t, yt := gen.Info.TypeOf(expr.X), gen.Info.TypeOf(expr.Y)
+    isValue := gen.NeedsClone(t) || IsInterface(t) || IsInterface(yt)
+    if (expr.Op == token.EQL || expr.Op == token.NEQ) && isValue && !gen.IsNilIdent(expr.X) && !gen.IsNilIdent(expr.Y) {
+        out := gen.GenEqual(gen.GenExpr(expr.X), t, gen.GenExpr(expr.Y), yt)
+        if expr.Op == token.NEQ {
+            out = "!" + out
+        }
+        return out
+    }
+
+    x := gen.GenExpr(expr.X)
+    y := gen.GenExpr(expr.Y)
+
+    switch expr.Op {
+        case token.EQL: return "(" + x + "===" + y + ")"
+        case token.NEQ: return "(" + x + "!==" + y + ")"
+        case token.LSS, token.GTR, token.LEQ, token.GEQ, token.LAND, token.LOR: {
+            return "(" + x + expr.Op.String() + y + ")"
+        }
+        default: return gen.GenArith(expr.Op, x, y, gen.Info.TypeOf(expr), gen.Info.TypeOf(expr.Y))
+    }
+}
+
+// GenEqual generates the go `==` of the js values x and y of types xt
+// and yt. Structs and arrays are compared by value and the concrete
+// side of an interface comparison is boxed to compare the dynamic types.
+func (gen *Gen) GenEqual(x string, xt types.Type, y string, yt types.Type) string {
+    if IsInterface(xt) || IsInterface(yt) {
+        t := xt
+        if !IsInterface(t) {
+            t = yt
+        }
+        return "$ifaceEq(" + gen.GenBox(x, xt, t) + "," + gen.GenBox(y, yt, t) + ")"
+    }
+    if gen.NeedsClone(xt) {
+        a, b := gen.Temp("a"), gen.Temp("b")
+        return "((" + a + "," + b + ")=>" + gen.EqualValue(a, b, xt) + ")(" + x + "," + y + ")"
+    }
+    return "(" + x + "===" + y + ")"
 }
 
 func (gen *Gen) GenBasicLit(expr *ast.BasicLit) string {
-    return expr.Value
+    value := gen.Info.Types[expr].Value
+    switch expr.Kind {
+        case token.STRING: return JsString(constant.StringVal(value))
+        case token.CHAR: return value.ExactString()
+        default: return expr.Value
+    }
 }
 
 func (gen *Gen) GenGenDecl(expr *ast.GenDecl) string {
     var out string
+    if expr.Tok == token.CONST {
+        // the uses of constants are their values, see GenConstant
+        return out
+    }
     for _, spec := range expr.Specs {
         out += gen.GenSpec(spec)
     }
@@ -310,11 +684,19 @@ func (gen *Gen) GenDeclStmt(expr *ast.DeclStmt) string {
     if isGenDecl {
         return gen.GenGenDecl(a)
     }
-    panic(fmt.Sprintf("GenDecl not implemented for (%v)", reflect.TypeOf(expr.Decl)))
+    return gen.Errorf(expr, "declaration not supported (%v)", reflect.TypeOf(expr.Decl))
 }
 
 func (gen *Gen) GenUnaryExpr(expr *ast.UnaryExpr) string {
-    return expr.Op.String() + gen.GenExpr(expr.X)
+    switch expr.Op {
+        case token.AND: return gen.GenAddress(expr.X)
+        case token.ARROW: return gen.GenRecv(expr)
+        case token.ADD: return gen.GenExpr(expr.X)
+        case token.SUB, token.XOR: {
+            return gen.GenNegate(expr.Op, gen.GenExpr(expr.X), gen.Info.TypeOf(expr))
+        }
+        default: return expr.Op.String() + gen.GenExpr(expr.X)
+    }
 }
 
 func (gen *Gen) GenSpec(expr ast.Spec) string {
@@ -322,41 +704,220 @@ func (gen *Gen) GenSpec(expr ast.Spec) string {
         case *ast.TypeSpec: return gen.GenTypeSpec(e)
         case *ast.ValueSpec: return gen.GenValueSpec(e)
         default: { 
-            panic(fmt.Sprintf("GenSpec not implemented for (%v)", reflect.TypeOf(expr)))
+            return gen.Errorf(expr, "specification not supported (%v)", reflect.TypeOf(expr))
+        }
+    }
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+    if paren, ok := expr.(*ast.ParenExpr); ok {
+        return unparen(paren.X)
+    }
+    return expr
+}
+
+func isBlank(expr ast.Expr) bool {
+    ident, ok := expr.(*ast.Ident)
+    return ok && ident.Name == "_"
+}
+
+// GenTuple generates a list of expressions as a js array, blank
+// identifiers are left empty so the array can be used as a
+// destructuring target.
+func (gen *Gen) GenTuple(exprs []ast.Expr) string {
+    var out string
+    out += "["
+    for i, expr := range exprs {
+        if !isBlank(expr) {
+            out += gen.GenExpr(expr)
+        }
+        if i < len(exprs) - 1 {
+            out += ","
         }
     }
+    out += "]"
+    return out
+}
+
+// GenValue generates an expression whose value is going to be stored
+// somewhere else, values that go copies on assignment are copied and
+// values stored as an interface type `to` are boxed. A nil `to` keeps
+// the type of the expression.
+func (gen *Gen) GenValue(expr ast.Expr, to types.Type) string {
+    out := gen.GenExpr(expr)
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return out
+    }
+    switch unparen(expr).(type) {
+        case *ast.CompositeLit, *ast.CallExpr: {}
+        default: out = gen.CloneValue(out, t)
+    }
+    return gen.GenBox(out, t, to)
+}
+
+// GenValues generates a list of values, the value i is stored as the
+// type to[i] if there is one.
+func (gen *Gen) GenValues(exprs []ast.Expr, to []types.Type) string {
+    var out string
+    for i, expr := range exprs {
+        var t types.Type
+        if i < len(to) {
+            t = to[i]
+        }
+        out += gen.GenValue(expr, t)
+        if i < len(exprs) - 1 {
+            out += ","
+        }
+    }
+    return out
 }
 
 func (gen *Gen) GenValueSpec(expr *ast.ValueSpec) string {
     var out string
+
+    gen.AddDepth()
+
+    if len(expr.Names) > 1 && len(expr.Values) == 1 {
+        // var a, b = f()
+        names := []ast.Expr{}
+        for _, name := range expr.Names {
+            names = append(names, name)
+        }
+        gen.DeclIdents(names)
+        out += gen.GenLet(names) + gen.GenTuple(names) + "=" + gen.GenExpr(expr.Values[0]) + ";"
+        gen.RemDepth()
+        return out + gen.GenPtrDecls(names)
+    }
+
     for i, name := range expr.Names {
-        out += "let "
+        if isBlank(name) {
+            if i < len(expr.Values) {
+                out += gen.GenExpr(expr.Values[i]) + ";"
+            }
+            continue
+        }
+        gen.DeclIdents([]ast.Expr{name})
+        out += gen.GenLet([]ast.Expr{name})
         out += gen.GenIdent(name)
         if i < len(expr.Values) {
             out += "="
-            out += gen.GenExpr(expr.Values[i])
+            out += gen.GenValue(expr.Values[i], gen.Info.TypeOf(name))
+        } else {
+            out += "=" + gen.ZeroValue(gen.Info.TypeOf(name))
         }
         out += ";"
+        out += gen.GenPtrDecls([]ast.Expr{name})
     }
+
+    gen.RemDepth()
     return out
 }
 
+// GenLet returns the `let` declaring the names, the names are added
+// to gen.hoisted instead when they are hoisted.
+func (gen *Gen) GenLet(names []ast.Expr) string {
+    if gen.loopVars {
+        return gen.LoopDecl()
+    }
+    if gen.hoisted == nil {
+        return "let "
+    }
+    for _, name := range names {
+        if !isBlank(name) {
+            *gen.hoisted = append(*gen.hoisted, gen.GenExpr(name))
+        }
+    }
+    return ""
+}
+
+// AssignOp returns the operator of an assignment `x op= y`.
+func AssignOp(tok token.Token) token.Token {
+    ops := map[token.Token]token.Token{
+        token.ADD_ASSIGN: token.ADD,
+        token.SUB_ASSIGN: token.SUB,
+        token.MUL_ASSIGN: token.MUL,
+        token.QUO_ASSIGN: token.QUO,
+        token.REM_ASSIGN: token.REM,
+        token.AND_ASSIGN: token.AND,
+        token.OR_ASSIGN: token.OR,
+        token.XOR_ASSIGN: token.XOR,
+        token.SHL_ASSIGN: token.SHL,
+        token.SHR_ASSIGN: token.SHR,
+        token.AND_NOT_ASSIGN: token.AND_NOT,
+    }
+    return ops[tok]
+}
+
+// GenOpAssign generates the value stored by `x op= y`.
+func (gen *Gen) GenOpAssign(expr *ast.AssignStmt, value string) string {
+    lhs := expr.Lhs[0]
+    return gen.GenArith(AssignOp(expr.Tok), gen.GenExpr(lhs), value, gen.Info.TypeOf(lhs), gen.Info.TypeOf(expr.Rhs[0]))
+}
+
 func (gen *Gen) GenAssignStmt(expr *ast.AssignStmt) string {
-    if len(expr.Lhs) < 1 { panic("missing lhs of assignment") }
-    if len(expr.Rhs) < 1 { panic("missing rhs of assignment") }
+    if len(expr.Lhs) < 1 { return gen.Errorf(expr, "missing lhs of assignment") }
+    if len(expr.Rhs) < 1 { return gen.Errorf(expr, "missing rhs of assignment") }
 
-    tok := expr.Tok.String()
-    if tok == ":=" {
-        tok = "="
+    if expr.Tok == token.DEFINE {
+        return gen.GenDefineStmt(expr)
     }
+    tok := expr.Tok.String()
 
     var out string
-    out += gen.GenExpr(expr.Lhs[0])
-    out += tok
 
     gen.AddDepth()
 
-    out += gen.GenExpr(expr.Rhs[0])
+    if len(expr.Lhs) == 1 && isBlank(expr.Lhs[0]) {
+        // _ = x only evaluates x
+        out += gen.GenExpr(expr.Rhs[0])
+    } else if len(expr.Lhs) == 1 && gen.IsStore(expr.Lhs[0]) {
+        value := gen.GenValue(expr.Rhs[0], gen.Info.TypeOf(expr.Lhs[0]))
+        if tok != "=" {
+            // x op= y
+            value = gen.GenOpAssign(expr, value)
+        }
+        out += gen.GenStore(expr.Lhs[0], value)
+    } else if len(expr.Lhs) == 1 && tok != "=" && !IsPlainArith(AssignOp(expr.Tok), gen.Info.TypeOf(expr.Lhs[0])) {
+        out += gen.GenExpr(expr.Lhs[0])
+        out += "="
+        out += gen.GenOpAssign(expr, gen.GenValue(expr.Rhs[0], nil))
+    } else if len(expr.Lhs) == 1 {
+        out += gen.GenExpr(expr.Lhs[0])
+        out += tok
+        out += gen.GenValue(expr.Rhs[0], gen.Info.TypeOf(expr.Lhs[0]))
+    } else if gen.HasStore(expr.Lhs) {
+        // the targets cannot be destructured, the values are stored
+        // one by one after evaluating the right side.
+        var stores string
+        for i, lhs := range expr.Lhs {
+            value := fmt.Sprintf("$t[%d]", i)
+            if isBlank(lhs) {
+                continue
+            } else if gen.IsStore(lhs) {
+                stores += gen.GenStore(lhs, value) + ";"
+            } else {
+                stores += gen.GenExpr(lhs) + "=" + value + ";"
+            }
+        }
+        out += "(($t)=>{" + stores + "})("
+        if len(expr.Rhs) == 1 {
+            out += gen.GenExpr(expr.Rhs[0])
+        } else {
+            out += "[" + gen.GenValues(expr.Rhs, gen.TypesOf(expr.Lhs)) + "]"
+        }
+        out += ")"
+    } else {
+        // the right side is evaluated into an array before any
+        // assignment so `a, b = b, a` swaps the values.
+        out += gen.GenTuple(expr.Lhs)
+        out += tok
+        if len(expr.Rhs) == 1 {
+            out += gen.GenExpr(expr.Rhs[0])
+        } else {
+            out += "[" + gen.GenValues(expr.Rhs, gen.TypesOf(expr.Lhs)) + "]"
+        }
+    }
 
     gen.RemDepth()
 
@@ -373,34 +934,115 @@ func isJsBindFunc(expr *ast.FuncDecl) bool {
            expr.Doc.List[0].Text[2:] == "js-bind"
 }
 
-func (gen *Gen) GenCall(expr *ast.CallExpr) string {
-    name := gen.GenExpr(expr.Fun)
-    sels := strings.Split(name, ".")
-    query := ""
-    out := ""
+// GenArgs generates the arguments of a call, the argument i is passed
+// as the type params[i] if there is one.
+func (gen *Gen) GenArgs(exprs []ast.Expr, params []types.Type) string {
+    var args string
+    for i, arg := range exprs {
+        var param types.Type
+        if i < len(params) {
+            param = params[i]
+        }
+        if _, ok := gen.Info.TypeOf(arg).(*types.Tuple); ok {
+            // f(g()) where g returns multiple values
+            args += "..."
+            param = nil
+        }
+        args += gen.GenValue(arg, param)
+        if i < len(exprs) - 1 {
+            args += ","
+        }
+    }
+    return args
+}
 
-    if _, ok := expr.Fun.(*ast.SelectorExpr); ok {
-        query = sels[len(sels) - 1]
+// GenCallArgs generates the arguments of a call to a go function, the
+// variadic arguments are passed as a slice.
+func (gen *Gen) GenCallArgs(expr *ast.CallExpr) string {
+    sig, ok := gen.Info.TypeOf(expr.Fun).Underlying().(*types.Signature)
+    if !ok {
+        return gen.GenArgs(expr.Args, nil)
+    }
+    params := TupleTypes(sig.Params())
+    if !sig.Variadic() || expr.Ellipsis.IsValid() {
+        return gen.GenArgs(expr.Args, params)
+    }
+    if len(expr.Args) == 1 {
+        if _, ok := gen.Info.TypeOf(expr.Args[0]).(*types.Tuple); ok {
+            // f(g()) where f is variadic
+            return "...$variadic(" + gen.GenExpr(expr.Args[0]) + "," + fmt.Sprint(sig.Params().Len()) + ")"
+        }
+    }
+    fixed := sig.Params().Len() - 1
+    var args string
+    if fixed > 0 {
+        args += gen.GenArgs(expr.Args[:fixed], params) + ","
+    }
+    if len(expr.Args) > fixed {
+        elem := ElemType(params[fixed])
+        args += "$sliceOf([" + gen.GenValues(expr.Args[fixed:], RepeatType(elem, len(expr.Args) - fixed)) + "])"
     } else {
-        query = name
+        args += "null"
     }
+    return args
+}
 
-    fun := gen.LookupFunc(query)
+func (gen *Gen) GenCall(expr *ast.CallExpr) string {
+    out := ""
+    // only the deferred call itself gets the panic
+    panicArg := gen.panicArg
+    gen.panicArg = ""
 
     gen.AddDepth()
 
-    var args string
-    for i, arg := range expr.Args {
-        args += gen.GenExpr(arg)
-        if i < len(expr.Args) - 1 {
-            args += ","
+    if builtin, ok := gen.ObjectOf(expr.Fun).(*types.Builtin); ok {
+        out = gen.GenBuiltinCall(builtin, expr)
+        gen.RemDepth()
+        if gen.AddSemicolon() {
+            out += ";"
         }
+        return out
     }
 
-    gen.RemDepth()
+    if tv := gen.Info.Types[expr.Fun]; tv.IsType() {
+        out = gen.GenConversion(expr.Args[0], tv.Type)
+        gen.RemDepth()
+        return out
+    }
+
+    name := gen.GenCallee(expr.Fun)
+    sels := strings.Split(name, ".")
+
+    fun := gen.LookupFunc(gen.ObjectOf(expr.Fun))
+
+    var args string
+    if !isJsBindFunc(fun) {
+        args = gen.GenCallArgs(expr)
+        if e, ok := unparen(expr.Fun).(*ast.SelectorExpr); ok && gen.IsRecvParam(e) {
+            args = joinArgs(gen.GenRecvArg(e), args)
+        }
+        if _, ok := gen.evaluated[expr.Fun]; !ok {
+            args = joinArgs(gen.GenCallTypeArgs(expr.Fun), args)
+        }
+        args = joinArgs(args, panicArg)
+    } else {
+        // bindings receive the arguments as js values
+        if expr.Ellipsis.IsValid() {
+            last := len(expr.Args) - 1
+            if last > 0 {
+                args = gen.GenJsArgs(expr.Args[:last]) + ","
+            }
+            args += "...$toArray(" + gen.GenExpr(expr.Args[last]) + ")"
+        } else {
+            args = gen.GenJsArgs(expr.Args)
+        }
+    }
 
     if !isJsBindFunc(fun) {
         out = name + "(" + args + ")"
+        if gen.IsAsyncCall(expr) {
+            out = "(await " + out + ")"
+        }
     } else {
         for _, doc := range fun.Doc.List[1:] {
             out += doc.Text[2:]
@@ -408,29 +1050,70 @@ func (gen *Gen) GenCall(expr *ast.CallExpr) string {
         out = strings.Replace(out, "%args%", args, 1)
         out = strings.Replace(out, "%recv%", strings.Join(sels[:len(sels)-1], "."), 1)
         for i, arg := range expr.Args {
-            out = strings.Replace(out, fmt.Sprintf("%%arg%d%%", i), gen.GenExpr(arg), -1)
+            if param := fmt.Sprintf("%%arg%d%%", i); strings.Contains(out, param) {
+                out = strings.Replace(out, param, gen.GenJsArgs([]ast.Expr{arg}), -1)
+            }
+        }
+        if gen.IsString(expr) {
+            out = "$fromJsString(" + out + ")"
         }
     }
 
+    gen.RemDepth()
+
     if gen.AddSemicolon() {
         out += ";"
     }
     return out
 }
 
-func (gen *Gen) GenField(expr *ast.Field) string {
-    if len(expr.Names) < 1 { panic("missing field name") }
-    return gen.GenIdent(expr.Names[0])
+// GenJsArgs generates the arguments of a call to a binding, interface
+// values are passed unboxed.
+func (gen *Gen) GenJsArgs(exprs []ast.Expr) string {
+    var args string
+    for i, arg := range exprs {
+        if tp, ok := gen.Info.TypeOf(arg).(*types.TypeParam); ok {
+            args += "$unbox(" + gen.GenBoxTypeParam(gen.GenExpr(arg), tp) + ")"
+        } else if IsInterface(gen.Info.TypeOf(arg)) {
+            args += "$unbox(" + gen.GenExpr(arg) + ")"
+        } else if gen.IsString(arg) {
+            args += "$toJsString(" + gen.GenExpr(arg) + ")"
+        } else if IsBigInt(gen.Info.TypeOf(arg)) {
+            args += "Number(" + gen.GenExpr(arg) + ")"
+        } else {
+            args += gen.GenArgs([]ast.Expr{arg}, nil)
+        }
+        if i < len(exprs) - 1 {
+            args += ","
+        }
+    }
+    return args
 }
 
-func (gen *Gen) GenExprStmt(expr *ast.ExprStmt) string {
-    return gen.GenExpr(expr.X)
+// GenField generates the parameters declared by a field of a parameter
+// list, unnamed and blank parameters get a name that is not used.
+func (gen *Gen) GenField(expr *ast.Field) string {
+    if len(expr.Names) == 0 {
+        return gen.Temp("_")
+    }
+    var names []string
+    for _, name := range expr.Names {
+        if isBlank(name) {
+            names = append(names, gen.Temp("_"))
+        } else {
+            names = append(names, gen.GenIdent(name))
+        }
+    }
+    return strings.Join(names, ",")
 }
 
-func (gen *Gen) GenSelector(expr *ast.SelectorExpr) string {
-    parent := gen.GenExpr(expr.X)
-    callee := gen.GenExpr(expr.Sel)
-    return parent + "." + callee
+func (gen *Gen) GenExprStmt(expr *ast.ExprStmt) string {
+    out := gen.GenExpr(expr.X)
+    if _, isCall := unparen(expr.X).(*ast.CallExpr); !isCall && gen.AddSemicolon() {
+        // calls end themselves, see GenCall
+        out += ";"
+    }
+    return out
 }
 
 func (gen *Gen) GenKeyValueExpr(expr *ast.KeyValueExpr) string {
@@ -441,6 +1124,9 @@ func (gen *Gen) GenKeyValueExpr(expr *ast.KeyValueExpr) string {
 
 func (gen *Gen) GenFields(fields *ast.FieldList) string {
     var out string
+    if fields == nil {
+        return out
+    }
     for i, param := range fields.List {
         out += gen.GenField(param)
         if i < len(fields.List) - 1 {
@@ -451,94 +1137,99 @@ func (gen *Gen) GenFields(fields *ast.FieldList) string {
 }
 
 func (gen *Gen) GenTypeSpec(expr *ast.TypeSpec) string {
-    var out string
-    a, isStruct := expr.Type.(*ast.StructType)
-    if isStruct {
-        out += "function "
-        out += expr.Name.Name
-        out += "("
-        if a.Fields.List != nil {
-            out += gen.GenFields(a.Fields)
+    if expr.Assign.IsValid() {
+        // aliases name an existing type
+        return ""
+    }
+    named := gen.Info.Defs[expr.Name].Type().(*types.Named)
+    switch t := named.Underlying().(type) {
+        case *types.Interface: {
+            // interfaces only exist for the type checker
+            return ""
         }
-        out += ")"
-        out += "{"
-        for _, field := range a.Fields.List {
-            field_str := gen.GenField(field)
-            out += "this." + field_str + "=" + field_str + ";"
+        case *types.Struct: {
+            return gen.GenStructType(JsName(expr.Name.Name), t, named.TypeParams()) + gen.GenPromotedMethods(named)
         }
-        out += "}"
-        return out
+        default: return gen.GenNamedType(JsName(expr.Name.Name))
     }
-    panic(fmt.Sprintf("GenTypeSpec not implemented for type (%v)", reflect.TypeOf(expr.Type)))
-}
-
-func (gen *Gen) LookupCompositeType(expr *ast.CompositeLit) *ast.TypeSpec {
-    return gen.LookupType(gen.GenExpr(expr.Type))
 }
 
+// GenStructConstructor generates a struct literal, keyed elements are
+// matched by field name and the omitted fields get their zero value.
 func (gen *Gen) GenStructConstructor(expr *ast.CompositeLit) string {
-    fields := ""
-    type_str := gen.GenExpr(expr.Type)
-
-    for i, field := range expr.Elts {
-        switch e := field.(type) {
-            case *ast.KeyValueExpr: {
-                fields += gen.GenExpr(e.Value)
-                if i < len(expr.Elts) - 1 {
-                    fields += ","
-                }
-            }
-            case *ast.BasicLit: {
-                fields += e.Value
-                if i < len(expr.Elts) - 1 {
-                    fields += ","
+    t := gen.Info.TypeOf(expr)
+    st := t.Underlying().(*types.Struct)
+    values := make([]string, st.NumFields())
+
+    gen.AddDepth()
+
+    for i, elt := range expr.Elts {
+        if kv, ok := elt.(*ast.KeyValueExpr); ok {
+            name := kv.Key.(*ast.Ident).Name
+            for j := 0; j < st.NumFields(); j++ {
+                if st.Field(j).Name() == name {
+                    values[j] = gen.GenValue(kv.Value, st.Field(j).Type())
                 }
             }
-            default: {}
+        } else {
+            values[i] = gen.GenValue(elt, st.Field(i).Type())
         }
     }
-    return "new " + type_str + "(" + fields + ")"
+
+    gen.RemDepth()
+
+    fields := ""
+    for i, value := range values {
+        field := st.Field(i)
+        if value == "" {
+            value = gen.ZeroValue(field.Type())
+        }
+        if _, isNamed := t.(*types.Named); !isNamed {
+            // anonymous structs are js objects
+            fields += field.Name() + ":"
+        }
+        fields += value
+        if i < len(values) - 1 {
+            fields += ","
+        }
+    }
+
+    if named, isNamed := t.(*types.Named); isNamed {
+        return "new " + gen.TypeName(named) + "(" + fields + ")"
+    }
+    return "{" + fields + "}"
 }
 
 func (gen *Gen) GenCompositeLit(expr *ast.CompositeLit) string {
-    type_decl := gen.LookupCompositeType(expr)
-    if type_decl != nil {
-        return gen.GenStructConstructor(expr);
+    if ptr, ok := gen.Info.TypeOf(expr).(*types.Pointer); ok {
+        // `{...}` is `&T{...}` inside of a composite literal of *T
+        // values, the copy of the literal has the type T
+        lit := *expr
+        gen.Info.Types[&lit] = types.TypeAndValue{Type: ptr.Elem()}
+        return gen.GenAddress(&lit)
+    }
+    if gen.IsMap(expr) {
+        return gen.GenMapLit(expr)
+    } else if gen.IsSlice(expr) {
+        return gen.GenSliceLit(expr)
+    } else if gen.IsArray(expr) {
+        return gen.GenArrayLit(expr)
+    } else if gen.IsStruct(gen.Info.TypeOf(expr)) {
+        return gen.GenStructConstructor(expr)
     } else {
-        left := "{"
-        right := "}"
         fields := ""
-
-        if _, ok := expr.Type.(*ast.ArrayType); ok {
-            left = "["
-            right = "]"
-        }
-
         for i, field := range expr.Elts {
             fields += gen.GenExpr(field)
             if i < len(expr.Elts) - 1 {
                 fields += ","
             }
         }
-
-        return left + fields + right
+        return "{" + fields + "}"
     }
 }
 
 func (gen *Gen) GenArrayType(expr *ast.ArrayType) string {
-    return "???"
-}
-
-func (gen *Gen) GenRangeStmt(expr *ast.RangeStmt) string {
-    var key string = gen.GenExpr(expr.Key)
-    var val string = gen.GenExpr(expr.Value)
-    var subj string = gen.GenExpr(expr.X)
-    var body string = gen.GenBlockStmt(expr.Body)
-
-    return fmt.Sprintf(
-        "for (let [%s, %s] of Object.entries(%s)) {%s}",
-        key, val, subj, body,
-    )
+    return gen.Errorf(expr, "array type used as a value")
 }
 
 func (gen *Gen) GenParenExpr(expr *ast.ParenExpr) string {
@@ -547,8 +1238,206 @@ func (gen *Gen) GenParenExpr(expr *ast.ParenExpr) string {
 
 func (gen *Gen) GenFuncLit(expr *ast.FuncLit) string {
     var out string
-    out += "() => {"
-    out += gen.GenBlockStmt(expr.Body)
+    if gen.async[expr.Body] {
+        out += "async "
+    }
+    out += "("
+    var recoverParam string
+    if gen.Recovers(expr.Body) {
+        recoverParam = "$deferPanic"
+    }
+    out += joinArgs(gen.GenFields(expr.Type.Params), recoverParam)
+    out += ") => {"
+
+    // the body is a list of statements even inside of an expression
+    // and the branches cannot leave it
+    depth, targets := gen.depth, gen.targets
+    gen.depth, gen.targets = 0, nil
+
+    gen.PushFunc(expr.Type, expr.Body)
+    out += gen.GenFuncBody(expr.Body)
+    gen.PopFunc()
+
+    gen.depth, gen.targets = depth, targets
+
     out += "}"
     return out
 }
+
+// GenFuncBody generates the body of the current function.
+func (gen *Gen) GenFuncBody(body *ast.BlockStmt) string {
+    fun := gen.CurrentFunc()
+    out := gen.GenResultDecls(fun.Type)
+    for _, list := range []*ast.FieldList{fun.Type.Params, fun.Type.Results} {
+        if list == nil {
+            continue
+        }
+        for _, field := range list.List {
+            for _, name := range field.Names {
+                out += gen.GenPtrDecls([]ast.Expr{name})
+            }
+        }
+    }
+    if fun.Defers {
+        return out + gen.GenDeferBody(body)
+    }
+    return out + gen.GenBlockStmt(body)
+}
+
+// GenResultDecls declares the named results of a function initialized
+// to their zero values.
+func (gen *Gen) GenResultDecls(fun *ast.FuncType) string {
+    var out string
+    if fun.Results == nil {
+        return out
+    }
+    for _, field := range fun.Results.List {
+        for _, name := range field.Names {
+            if isBlank(name) {
+                continue
+            }
+            out += "let " + gen.GenIdent(name) + "=" + gen.ZeroValue(gen.Info.TypeOf(field.Type)) + ";"
+        }
+    }
+    return out
+}
+
+// ResultTypes returns the types of the results of a function.
+func (gen *Gen) ResultTypes(fun *ast.FuncType) []types.Type {
+    var out []types.Type
+    if fun.Results == nil {
+        return out
+    }
+    for _, field := range fun.Results.List {
+        t := gen.Info.TypeOf(field.Type)
+        out = append(out, t)
+        for i := 1; i < len(field.Names); i++ {
+            out = append(out, t)
+        }
+    }
+    return out
+}
+
+// TypesOf returns the types of a list of expressions.
+func (gen *Gen) TypesOf(exprs []ast.Expr) []types.Type {
+    var out []types.Type
+    for _, expr := range exprs {
+        out = append(out, gen.Info.TypeOf(expr))
+    }
+    return out
+}
+
+// TupleTypes returns the types of the variables of a tuple.
+func TupleTypes(tuple *types.Tuple) []types.Type {
+    var out []types.Type
+    for i := 0; i < tuple.Len(); i++ {
+        out = append(out, tuple.At(i).Type())
+    }
+    return out
+}
+
+// RepeatType returns a list with n times the type t.
+func RepeatType(t types.Type, n int) []types.Type {
+    var out []types.Type
+    for i := 0; i < n; i++ {
+        out = append(out, t)
+    }
+    return out
+}
+
+// NamedResults returns the js expressions of the named results of a
+// function, blank results are always their zero value.
+func (gen *Gen) NamedResults(fun *ast.FuncType) []string {
+    var out []string
+    if fun.Results == nil {
+        return out
+    }
+    for _, field := range fun.Results.List {
+        for _, name := range field.Names {
+            if isBlank(name) {
+                out = append(out, gen.ZeroValue(gen.Info.TypeOf(field.Type)))
+            } else {
+                out = append(out, gen.GenIdent(name))
+            }
+        }
+    }
+    return out
+}
+
+func (gen *Gen) GenIndexExpr(expr *ast.IndexExpr) string {
+    if gen.FuncTypeArgs(expr) != nil {
+        return gen.GenFuncInstance(expr)
+    }
+    if gen.IsMap(expr.X) {
+        return gen.GenMapIndex(expr)
+    } else if gen.IsSlice(expr.X) || gen.IsArray(expr.X) || gen.IsArrayPtr(expr.X) {
+        return gen.GenSliceIndex(expr)
+    } else if gen.IsString(expr.X) {
+        return gen.GenStringIndex(expr)
+    }
+    gen.AddDepth()
+    out := gen.GenExpr(expr.X) + "[" + gen.GenExpr(expr.Index) + "]"
+    gen.RemDepth()
+    return out
+}
+
+// IsStore reports if assigning to expr cannot be done with a plain js
+// assignment and GenStore must be used instead.
+func (gen *Gen) IsStore(expr ast.Expr) bool {
+    switch e := expr.(type) {
+        case *ast.ParenExpr: return gen.IsStore(e.X)
+        case *ast.IndexExpr: return gen.IsMap(e.X) || gen.IsSlice(e.X) || gen.IsArray(e.X) || gen.IsArrayPtr(e.X)
+        case *ast.StarExpr: return true
+        case *ast.Ident: {
+            // a new variable is not stored in place
+            return !isBlank(e) && gen.Info.Defs[e] == nil && (gen.IsStruct(gen.Info.TypeOf(e)) || gen.IsArray(e))
+        }
+        case *ast.SelectorExpr: return gen.IsStruct(gen.Info.TypeOf(e)) || gen.IsArray(e)
+        default: return false
+    }
+}
+
+func (gen *Gen) HasStore(exprs []ast.Expr) bool {
+    for _, expr := range exprs {
+        if gen.IsStore(expr) {
+            return true
+        }
+    }
+    return false
+}
+
+// GenStore generates the assignment of the js expression value to
+// expr, expr must be a target where IsStore is true.
+func (gen *Gen) GenStore(expr ast.Expr, value string) string {
+    if index, ok := expr.(*ast.IndexExpr); ok && gen.IsMap(index.X) {
+        return gen.GenMapStore(index, value)
+    }
+    if gen.IsStruct(gen.Info.TypeOf(expr)) {
+        // structs are updated in place so pointers to them see the new
+        // value
+        gen.AddDepth()
+        out := gen.GenStructSet(gen.GenExpr(expr), value, gen.Info.TypeOf(expr))
+        gen.RemDepth()
+        return out
+    }
+    if gen.IsArray(expr) {
+        // arrays too, pointers to their elements see the new values
+        gen.AddDepth()
+        out := gen.GenArraySet(gen.GenExpr(expr), value, gen.Info.TypeOf(expr))
+        gen.RemDepth()
+        return out
+    }
+    switch e := expr.(type) {
+        case *ast.ParenExpr: return gen.GenStore(e.X, value)
+        case *ast.StarExpr: {
+            gen.AddDepth()
+            out := gen.GenExpr(e.X) + ".$set(" + value + ")"
+            gen.RemDepth()
+            return out
+        }
+        case *ast.IndexExpr: return gen.GenSliceStore(e, value)
+        default: {
+            return gen.Errorf(expr, "cannot assign to (%v)", reflect.TypeOf(expr))
+        }
+    }
+}
@@ -0,0 +1,414 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "go/token"
+    "strings"
+    "go/types"
+)
+
+// Generic functions and types are generated once for every type
+// argument, the type arguments are erased. Generic functions and the
+// methods of generic types take the runtime descriptors of their type
+// arguments as their first arguments and the operations that depend on
+// a type parameter go through its descriptor, see runtime.js.
+
+func IsTypeParam(t types.Type) bool {
+    _, ok := t.(*types.TypeParam)
+    return ok
+}
+
+// TypeParamDesc returns the js name of the descriptor of the type
+// argument of a type parameter.
+func TypeParamDesc(t *types.TypeParam) string {
+    return "$tp" + t.Obj().Name()
+}
+
+// HasTypeParam reports if the type is built from a type parameter, its
+// descriptor is made when the code runs.
+func HasTypeParam(t types.Type) bool {
+    switch u := t.(type) {
+        case *types.TypeParam: return true
+        case *types.Named: {
+            args := u.TypeArgs()
+            for i := 0; args != nil && i < args.Len(); i++ {
+                if HasTypeParam(args.At(i)) {
+                    return true
+                }
+            }
+            return false
+        }
+        case *types.Pointer: return HasTypeParam(u.Elem())
+        case *types.Slice: return HasTypeParam(u.Elem())
+        case *types.Array: return HasTypeParam(u.Elem())
+        case *types.Chan: return HasTypeParam(u.Elem())
+        case *types.Map: return HasTypeParam(u.Key()) || HasTypeParam(u.Elem())
+        case *types.Signature: return HasTypeParam(u.Params()) || HasTypeParam(u.Results())
+        case *types.Tuple: {
+            for i := 0; i < u.Len(); i++ {
+                if HasTypeParam(u.At(i).Type()) {
+                    return true
+                }
+            }
+            return false
+        }
+        case *types.Struct: {
+            for i := 0; i < u.NumFields(); i++ {
+                if HasTypeParam(u.Field(i).Type()) {
+                    return true
+                }
+            }
+            return false
+        }
+        default: return false
+    }
+}
+
+// CoreType returns the underlying type of a type, the underlying type
+// of a type parameter is the one shared by the types of its constraint
+// like the `[]E` of `S ~[]E`. It is the constraint when they differ.
+func CoreType(t types.Type) types.Type {
+    param, ok := t.(*types.TypeParam)
+    if !ok {
+        return t.Underlying()
+    }
+    iface := param.Constraint().Underlying().(*types.Interface)
+    var core types.Type
+    for _, term := range constraintTerms(iface) {
+        u := term.Type().Underlying()
+        if core != nil && !types.Identical(core, u) {
+            return iface
+        }
+        core = u
+    }
+    if core == nil {
+        return iface
+    }
+    return core
+}
+
+// constraintTerms returns the terms of the unions of a constraint and
+// of the constraints it embeds.
+func constraintTerms(iface *types.Interface) []*types.Term {
+    var terms []*types.Term
+    for i := 0; i < iface.NumEmbeddeds(); i++ {
+        switch e := iface.EmbeddedType(i).(type) {
+            case *types.Union: {
+                for j := 0; j < e.Len(); j++ {
+                    terms = append(terms, e.Term(j))
+                }
+            }
+            default: {
+                if embedded, ok := e.Underlying().(*types.Interface); ok {
+                    terms = append(terms, constraintTerms(embedded)...)
+                } else {
+                    terms = append(terms, types.NewTerm(false, e))
+                }
+            }
+        }
+    }
+    return terms
+}
+
+// GenTypeParams generates the parameters that receive the descriptors
+// of the type arguments.
+func GenTypeParams(list *types.TypeParamList) string {
+    var names []string
+    for i := 0; list != nil && i < list.Len(); i++ {
+        names = append(names, TypeParamDesc(list.At(i)))
+    }
+    return strings.Join(names, ",")
+}
+
+// GenTypeArgs generates the descriptors of the type arguments passed to
+// a generic function or method.
+func (gen *Gen) GenTypeArgs(list *types.TypeList) string {
+    var descs []string
+    for i := 0; list != nil && i < list.Len(); i++ {
+        descs = append(descs, gen.TypeDesc(list.At(i)))
+    }
+    return strings.Join(descs, ",")
+}
+
+// NamedTypeArgs returns the type arguments of an instance of a generic
+// type, it is nil for other types.
+func NamedTypeArgs(t types.Type) *types.TypeList {
+    if named, ok := t.(*types.Named); ok {
+        return named.TypeArgs()
+    }
+    return nil
+}
+
+// joinArgs joins the non empty lists of js arguments.
+func joinArgs(lists ...string) string {
+    var args []string
+    for _, list := range lists {
+        if list != "" {
+            args = append(args, list)
+        }
+    }
+    return strings.Join(args, ",")
+}
+
+// OriginFunc maps a method of an instance of a generic type to the
+// method declared by the generic type. It does not handle instances of
+// generic functions, the uses of their names already refer to the
+// declared function. Unlike types.Func.Origin it works with go 1.18.
+func OriginFunc(fun *types.Func) *types.Func {
+    recv := fun.Type().(*types.Signature).Recv()
+    if recv == nil {
+        return fun
+    }
+    named := RecvType(recv.Type())
+    if named == nil || named.Origin() == named {
+        return fun
+    }
+    origin := named.Origin()
+    for i := 0; i < origin.NumMethods(); i++ {
+        if origin.Method(i).Name() == fun.Name() {
+            return origin.Method(i)
+        }
+    }
+    return fun
+}
+
+// FuncTypeArgs returns the type arguments of an instance of a generic
+// function, it is nil for other expressions.
+func (gen *Gen) FuncTypeArgs(expr ast.Expr) *types.TypeList {
+    switch e := unparen(expr).(type) {
+        case *ast.IndexExpr: return gen.FuncTypeArgs(e.X)
+        case *ast.IndexListExpr: return gen.FuncTypeArgs(e.X)
+        case *ast.SelectorExpr: return gen.FuncTypeArgs(e.Sel)
+        case *ast.Ident: {
+            if _, ok := gen.Info.Uses[e].(*types.Func); ok {
+                return gen.Info.Instances[e].TypeArgs
+            }
+        }
+        default: {}
+    }
+    return nil
+}
+
+// GenCallTypeArgs generates the descriptors of the type arguments passed
+// first to a call of a generic function or of a method of a generic
+// type.
+func (gen *Gen) GenCallTypeArgs(fun ast.Expr) string {
+    if targs := gen.FuncTypeArgs(fun); targs != nil {
+        return gen.GenTypeArgs(targs)
+    }
+    if e, ok := unparen(fun).(*ast.SelectorExpr); ok {
+        if sel := gen.Info.Selections[e]; sel != nil && sel.Kind() == types.MethodVal {
+            return gen.GenTypeArgs(gen.RecvTypeArgs(sel))
+        }
+    }
+    return ""
+}
+
+// GenFuncInstance generates an instance of a generic function used as a
+// value, the descriptors of the type arguments are bound to it.
+func (gen *Gen) GenFuncInstance(expr ast.Expr) string {
+    return gen.FuncName(gen.ObjectOf(expr)) + ".bind(null," + gen.GenTypeArgs(gen.FuncTypeArgs(expr)) + ")"
+}
+
+// FuncName returns the js name of a package level function.
+func (gen *Gen) FuncName(obj types.Object) string {
+    if obj.Pkg() == nil || gen.IsLocal(obj) {
+        return JsName(obj.Name())
+    }
+    return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// RecvTypeArgs returns the type arguments of the generic type of the
+// receiver of a selected method, the methods of interfaces and bindings
+// do not take them.
+func (gen *Gen) RecvTypeArgs(sel *types.Selection) *types.TypeList {
+    named := RecvType(sel.Recv())
+    if named == nil || IsInterface(named) || gen.IsJsType(named) {
+        return nil
+    }
+    return named.TypeArgs()
+}
+
+// GenBoxTypeParam generates the interface of the value of a type
+// parameter, the methods of its constraint are called on it.
+func (gen *Gen) GenBoxTypeParam(value string, t *types.TypeParam) string {
+    return "$boxAs(" + value + "," + TypeParamDesc(t) + ")"
+}
+
+// typeString builds the js expression of the go name of a type, the
+// parts that depend on type parameters are taken from their
+// descriptors.
+type typeString struct {
+    static string
+    parts []string
+}
+
+func (s *typeString) str(str string) {
+    s.static += str
+}
+
+func (s *typeString) expr(expr string) {
+    if s.static != "" {
+        s.parts = append(s.parts, JsString(s.static))
+        s.static = ""
+    }
+    s.parts = append(s.parts, expr)
+}
+
+func (s *typeString) js() string {
+    parts := s.parts
+    if s.static != "" || len(parts) == 0 {
+        parts = append(parts, JsString(s.static))
+    }
+    return strings.Join(parts, "+")
+}
+
+// TypeKey returns a js expression of the string that identifies the
+// type in the descriptors made by $typeFor, with key false it is the
+// go name of the type.
+func (gen *Gen) TypeKey(t types.Type, key bool) string {
+    var s typeString
+    gen.writeType(&s, t, key)
+    return s.js()
+}
+
+func (gen *Gen) writeType(s *typeString, t types.Type, key bool) {
+    switch u := t.(type) {
+        case *types.TypeParam: {
+            if key {
+                s.expr(TypeParamDesc(u) + ".key")
+            } else {
+                s.expr(TypeParamDesc(u) + ".name")
+            }
+        }
+        case *types.Basic: s.str(types.Typ[u.Kind()].Name())
+        case *types.Named: {
+            obj := u.Obj()
+            if obj.Pkg() != nil {
+                s.str(obj.Pkg().Name() + ".")
+            }
+            s.str(obj.Name())
+            if key && obj.Pkg() != nil && obj.Parent() != obj.Pkg().Scope() {
+                // local types of different scopes can have the same name
+                s.str(fmt.Sprintf("#%d", obj.Pos()))
+            }
+            if args := u.TypeArgs(); args != nil && args.Len() > 0 {
+                s.str("[")
+                for i := 0; i < args.Len(); i++ {
+                    if i > 0 {
+                        s.str(",")
+                    }
+                    gen.writeType(s, args.At(i), key)
+                }
+                s.str("]")
+            }
+        }
+        case *types.Pointer: {
+            s.str("*")
+            gen.writeType(s, u.Elem(), key)
+        }
+        case *types.Slice: {
+            s.str("[]")
+            gen.writeType(s, u.Elem(), key)
+        }
+        case *types.Array: {
+            s.str(fmt.Sprintf("[%d]", u.Len()))
+            gen.writeType(s, u.Elem(), key)
+        }
+        case *types.Map: {
+            s.str("map[")
+            gen.writeType(s, u.Key(), key)
+            s.str("]")
+            gen.writeType(s, u.Elem(), key)
+        }
+        case *types.Chan: {
+            switch u.Dir() {
+                case types.SendOnly: s.str("chan<- ")
+                case types.RecvOnly: s.str("<-chan ")
+                default: s.str("chan ")
+            }
+            gen.writeType(s, u.Elem(), key)
+        }
+        case *types.Signature: {
+            s.str("func(")
+            params := u.Params()
+            for i := 0; i < params.Len(); i++ {
+                if i > 0 {
+                    s.str(", ")
+                }
+                if u.Variadic() && i == params.Len() - 1 {
+                    s.str("...")
+                    gen.writeType(s, params.At(i).Type().(*types.Slice).Elem(), key)
+                } else {
+                    gen.writeType(s, params.At(i).Type(), key)
+                }
+            }
+            s.str(")")
+            results := u.Results()
+            if results.Len() == 1 {
+                s.str(" ")
+                gen.writeType(s, results.At(0).Type(), key)
+            } else if results.Len() > 1 {
+                s.str(" (")
+                for i := 0; i < results.Len(); i++ {
+                    if i > 0 {
+                        s.str(", ")
+                    }
+                    gen.writeType(s, results.At(i).Type(), key)
+                }
+                s.str(")")
+            }
+        }
+        default: s.str(TypeString(t))
+    }
+}
+
+// arithOps are the binary operators of the descriptors of numbers.
+var arithOps = []token.Token{
+    token.ADD, token.SUB, token.MUL, token.QUO, token.REM,
+    token.AND, token.OR, token.XOR, token.AND_NOT, token.SHL, token.SHR,
+}
+
+// GenTypeProps generates the props of the descriptor of a type, see
+// runtime.js.
+func (gen *Gen) GenTypeProps(t types.Type) string {
+    var props []string
+    props = append(props, "key:" + gen.TypeKey(t, true))
+    if IsInterface(t) {
+        return strings.Join(append(props, "iface:true", "sigs:" + gen.MethodSigs(t)), ",")
+    }
+    if types.NewMethodSet(t).Len() > 0 {
+        props = append(props, "sigs:" + gen.MethodSigs(t))
+    }
+    props = append(props, "zero:" + gen.ZeroFunc(t))
+    if gen.NeedsClone(t) {
+        props = append(props, "clone:(v)=>" + gen.CloneValue("v", t))
+    }
+    if hash := gen.MapHash(t); hash != "null" {
+        props = append(props, "mapHash:" + hash)
+    }
+    if basic := basicOf(t); basic != nil && basic.Info() & types.IsNumeric != 0 {
+        props = append(props, "conv:(v)=>" + gen.GenNumericConversion("v", types.Typ[types.Float64], t))
+        var ops []string
+        for _, op := range arithOps {
+            if basic.Info() & types.IsInteger == 0 && op != token.ADD && op != token.SUB && op != token.MUL && op != token.QUO {
+                continue
+            }
+            ops = append(ops, "\"" + op.String() + "\":(x,y)=>" + gen.GenArith(op, "x", "y", t, t))
+        }
+        ops = append(ops, "neg:(x)=>" + gen.GenNegate(token.SUB, "x", t))
+        if basic.Info() & types.IsInteger != 0 {
+            ops = append(ops, "not:(x)=>" + gen.GenNegate(token.XOR, "x", t))
+        }
+        props = append(props, "ops:{" + strings.Join(ops, ",") + "}")
+    } else if IsString(t) {
+        props = append(props, "ops:{\"+\":(x,y)=>x+y}")
+    }
+    return strings.Join(props, ",")
+}
+
+// GenTypeArith generates `x op y` on values of a type parameter.
+func GenTypeArith(op token.Token, x string, y string, t *types.TypeParam) string {
+    return TypeParamDesc(t) + ".ops[\"" + op.String() + "\"](" + x + "," + y + ")"
+}
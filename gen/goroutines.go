@@ -0,0 +1,338 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "strings"
+    "go/token"
+    "go/types"
+)
+
+// Goroutines run on the async functions of js, see runtime.js for the
+// scheduler and the channels. The functions that can block are found
+// before generating a package, they are async and their calls are
+// awaited.
+
+func (gen *Gen) IsChan(expr ast.Expr) bool {
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return false
+    }
+    _, ok := CoreType(t).(*types.Chan)
+    return ok
+}
+
+func (gen *Gen) ChanType(expr ast.Expr) *types.Chan {
+    return CoreType(gen.Info.TypeOf(expr)).(*types.Chan)
+}
+
+// FindAsync finds the function bodies of the files that can block, a
+// body blocks if it uses a channel or calls a function that blocks.
+// Calls through function values and interfaces block if a function of
+// the same signature blocks.
+func (gen *Gen) FindAsync(files []*ast.File) {
+    gen.async = map[*ast.BlockStmt]bool{}
+    gen.asyncSigs = nil
+
+    sigs := map[*ast.BlockStmt]*types.Signature{}
+    var bodies []*ast.BlockStmt
+    for _, file := range files {
+        ast.Inspect(file, func(node ast.Node) bool {
+            switch e := node.(type) {
+                case *ast.FuncDecl: {
+                    if e.Body != nil {
+                        bodies = append(bodies, e.Body)
+                        sigs[e.Body], _ = gen.Info.Defs[e.Name].Type().(*types.Signature)
+                    }
+                }
+                case *ast.FuncLit: {
+                    bodies = append(bodies, e.Body)
+                    sigs[e.Body], _ = gen.Info.TypeOf(e).(*types.Signature)
+                }
+                default: {}
+            }
+            return true
+        })
+    }
+
+    for changed := true; changed; {
+        changed = false
+        for _, body := range bodies {
+            if !gen.async[body] && gen.Blocks(body) {
+                gen.async[body] = true
+                if sigs[body] != nil {
+                    gen.asyncSigs = append(gen.asyncSigs, sigs[body])
+                }
+                changed = true
+            }
+        }
+    }
+}
+
+// Blocks reports if a function body can block, the function literals
+// and the `go` statements of the body run on their own.
+func (gen *Gen) Blocks(body *ast.BlockStmt) bool {
+    found := false
+    ast.Inspect(body, func(node ast.Node) bool {
+        if found {
+            // the siblings of the node that blocks are still visited
+            return false
+        }
+        switch e := node.(type) {
+            case *ast.FuncLit, *ast.GoStmt: return false
+            case *ast.SendStmt, *ast.SelectStmt: found = true
+            case *ast.UnaryExpr: found = e.Op == token.ARROW
+            case *ast.RangeStmt: {
+                _, isFunc := gen.Info.TypeOf(e.X).Underlying().(*types.Signature)
+                found = gen.IsChan(e.X) || isFunc && gen.IsAsyncCall(&ast.CallExpr{Fun: e.X})
+            }
+            case *ast.CallExpr: found = gen.IsAsyncCall(e)
+            default: {}
+        }
+        return !found
+    })
+    return found
+}
+
+// IsAsyncCall reports if a call can block, bindings block when their
+// template awaits.
+func (gen *Gen) IsAsyncCall(expr *ast.CallExpr) bool {
+    if gen.Info.Types[expr.Fun].IsType() {
+        return false
+    }
+    obj := gen.ObjectOf(expr.Fun)
+    if _, ok := obj.(*types.Builtin); ok {
+        return false
+    }
+    if _, ok := obj.(*types.Func); ok {
+        if fun := gen.LookupFunc(obj); fun != nil {
+            if isJsBindFunc(fun) {
+                return strings.HasPrefix(fun.Doc.List[1].Text[2:], "await ")
+            }
+            return gen.async[fun.Body]
+        }
+    }
+    // a function value or an interface method
+    sig, ok := gen.Info.TypeOf(expr.Fun).Underlying().(*types.Signature)
+    if !ok {
+        return false
+    }
+    for _, async := range gen.asyncSigs {
+        if MatchType(sig, async) {
+            return true
+        }
+    }
+    return false
+}
+
+// MatchType reports if a type built from type parameters can be
+// instantiated to another type, a type parameter matches any type. A
+// call through a function value of a generic function blocks if it can
+// receive a function that blocks.
+func MatchType(pattern types.Type, t types.Type) bool {
+    if !HasTypeParam(pattern) {
+        return types.Identical(pattern, t)
+    }
+    switch p := pattern.(type) {
+        case *types.TypeParam: return true
+        case *types.Named: {
+            named, ok := t.(*types.Named)
+            if !ok || named.Origin() != p.Origin() {
+                return false
+            }
+            for i := 0; i < p.TypeArgs().Len(); i++ {
+                if !MatchType(p.TypeArgs().At(i), named.TypeArgs().At(i)) {
+                    return false
+                }
+            }
+            return true
+        }
+        case *types.Pointer: {
+            ptr, ok := t.(*types.Pointer)
+            return ok && MatchType(p.Elem(), ptr.Elem())
+        }
+        case *types.Slice: {
+            slice, ok := t.(*types.Slice)
+            return ok && MatchType(p.Elem(), slice.Elem())
+        }
+        case *types.Array: {
+            array, ok := t.(*types.Array)
+            return ok && p.Len() == array.Len() && MatchType(p.Elem(), array.Elem())
+        }
+        case *types.Chan: {
+            ch, ok := t.(*types.Chan)
+            return ok && p.Dir() == ch.Dir() && MatchType(p.Elem(), ch.Elem())
+        }
+        case *types.Map: {
+            m, ok := t.(*types.Map)
+            return ok && MatchType(p.Key(), m.Key()) && MatchType(p.Elem(), m.Elem())
+        }
+        case *types.Signature: {
+            sig, ok := t.(*types.Signature)
+            return ok && p.Variadic() == sig.Variadic() &&
+                MatchType(p.Params(), sig.Params()) && MatchType(p.Results(), sig.Results())
+        }
+        case *types.Tuple: {
+            tuple, ok := t.(*types.Tuple)
+            if !ok || p.Len() != tuple.Len() {
+                return false
+            }
+            for i := 0; i < p.Len(); i++ {
+                if !MatchType(p.At(i).Type(), tuple.At(i).Type()) {
+                    return false
+                }
+            }
+            return true
+        }
+        case *types.Struct: {
+            st, ok := t.(*types.Struct)
+            if !ok || p.NumFields() != st.NumFields() {
+                return false
+            }
+            for i := 0; i < p.NumFields(); i++ {
+                if p.Field(i).Name() != st.Field(i).Name() || !MatchType(p.Field(i).Type(), st.Field(i).Type()) {
+                    return false
+                }
+            }
+            return true
+        }
+        default: return false
+    }
+}
+
+// GenGoStmt generates `go f(args)`, like a deferred call the function
+// and the arguments are evaluated by the statement.
+func (gen *Gen) GenGoStmt(stmt *ast.GoStmt) string {
+    return "$go(" + gen.GenDeferredCall(stmt.Call) + ");"
+}
+
+func (gen *Gen) GenSendStmt(stmt *ast.SendStmt) string {
+    gen.AddDepth()
+    out := "await $send(" + gen.GenExpr(stmt.Chan) + "," + gen.GenValue(stmt.Value, gen.ChanType(stmt.Chan).Elem()) + ")"
+    gen.RemDepth()
+    if gen.AddSemicolon() {
+        out += ";"
+    }
+    return out
+}
+
+// GenRecv generates `<-ch` and `v, ok := <-ch`.
+func (gen *Gen) GenRecv(expr *ast.UnaryExpr) string {
+    gen.AddDepth()
+    out := "(await $recv(" + gen.GenExpr(expr.X) + "," + gen.ZeroValue(gen.ChanType(expr.X).Elem()) + "))"
+    gen.RemDepth()
+    if _, ok := gen.Info.TypeOf(expr).(*types.Tuple); !ok {
+        out += "[0]"
+    }
+    return out
+}
+
+func (gen *Gen) GenChanRange(expr *ast.RangeStmt) string {
+    elem := gen.ChanType(expr.X).Elem()
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    gen.RemDepth()
+
+    r := gen.Temp("r")
+    var out string
+    out += "for (;;) {"
+    out += "const " + r + "=await $recv(" + subj + "," + gen.ZeroValue(elem) + ");"
+    out += "if (!" + r + "[1]){break;}"
+    key, _, prologue := gen.GenRangeVars(expr, elem, nil)
+    if key != "" {
+        out += gen.LoopDecl() + key + "=" + r + "[0];" + prologue
+    }
+    out += gen.GenBlockStmt(expr.Body)
+    out += "}"
+    return out
+}
+
+// GenSelectStmt generates a select as a chain of checks of the case
+// chosen by the runtime, the chain is wrapped in a js switch so
+// `break` leaves it.
+func (gen *Gen) GenSelectStmt(stmt *ast.SelectStmt) string {
+    var cases []string
+    var def *ast.CommClause
+    var clauses []*ast.CommClause
+
+    gen.AddDepth()
+
+    for _, clause := range stmt.Body.List {
+        comm := clause.(*ast.CommClause)
+        switch e := comm.Comm.(type) {
+            case nil: {
+                def = comm
+                continue
+            }
+            case *ast.SendStmt: {
+                value := gen.GenValue(e.Value, gen.ChanType(e.Chan).Elem())
+                cases = append(cases, "[" + gen.GenExpr(e.Chan) + "," + value + ",true]")
+            }
+            default: {
+                recv := gen.CommRecv(comm)
+                cases = append(cases, "[" + gen.GenExpr(recv.X) + "," + gen.ZeroValue(gen.ChanType(recv.X).Elem()) + "]")
+            }
+        }
+        clauses = append(clauses, comm)
+    }
+
+    gen.RemDepth()
+
+    i, r := gen.Temp("i"), gen.Temp("r")
+    var out string
+    out += "switch (0) {default:"
+    out += fmt.Sprintf("const [%s,%s]=await $select([%s],%v);", i, r, strings.Join(cases, ","), def != nil)
+
+    var chain string
+    for n, comm := range clauses {
+        if chain != "" {
+            chain += "else "
+        }
+        chain += fmt.Sprintf("if (%s===%d){", i, n)
+        if _, isSend := comm.Comm.(*ast.SendStmt); !isSend {
+            chain += gen.GenCommRecv(comm, r)
+        }
+        chain += gen.GenBlockStmt(&ast.BlockStmt{List: comm.Body})
+        chain += "}"
+    }
+    if def != nil {
+        body := gen.GenBlockStmt(&ast.BlockStmt{List: def.Body})
+        if chain == "" {
+            chain = body
+        } else {
+            chain += "else{" + body + "}"
+        }
+    }
+
+    out += chain
+    out += "}"
+    return out
+}
+
+// CommRecv returns the receive operation of a select case.
+func (gen *Gen) CommRecv(comm *ast.CommClause) *ast.UnaryExpr {
+    switch e := comm.Comm.(type) {
+        case *ast.ExprStmt: return unparen(e.X).(*ast.UnaryExpr)
+        case *ast.AssignStmt: return unparen(e.Rhs[0]).(*ast.UnaryExpr)
+        default: return nil
+    }
+}
+
+// GenCommRecv generates the assignment of a receiving select case from
+// the `[value, ok]` received by the runtime.
+func (gen *Gen) GenCommRecv(comm *ast.CommClause, received string) string {
+    assign, ok := comm.Comm.(*ast.AssignStmt)
+    if !ok {
+        return ""
+    }
+    recv := gen.CommRecv(comm)
+    if _, isTuple := gen.Info.TypeOf(recv).(*types.Tuple); isTuple {
+        gen.Evaluated(recv, received)
+    } else {
+        gen.Evaluated(recv, received + "[0]")
+    }
+    out := gen.GenAssignStmt(assign)
+    delete(gen.evaluated, recv)
+    return out
+}
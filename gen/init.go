@@ -0,0 +1,119 @@
+package gen
+
+import (
+    "go/ast"
+    "strings"
+    "go/types"
+)
+
+// Package initialization follows the go spec, the package variables
+// are declared with their zero value where they appear and initialized
+// after every declaration of the package in the order computed by the
+// type checker, then the `init` functions run in source order and
+// finally `main` is called.
+
+// GenPkgVars declares the variables of a package level `var`
+// declaration, their values are assigned by GenPkgInit.
+func (gen *Gen) GenPkgVars(decl *ast.GenDecl) string {
+    var out string
+    for _, spec := range decl.Specs {
+        for _, name := range spec.(*ast.ValueSpec).Names {
+            if isBlank(name) {
+                continue
+            }
+            gen.DeclIdents([]ast.Expr{name})
+            out += "let " + gen.GenIdent(name) + "=" + gen.ZeroValue(gen.Info.TypeOf(name)) + ";"
+            out += gen.GenPtrDecls([]ast.Expr{name})
+        }
+    }
+    return out
+}
+
+// GenInitFunc names an `init` function, a package can declare many of
+// them and they cannot be referred.
+func (gen *Gen) GenInitFunc(fun *ast.FuncDecl) string {
+    name := gen.Temp("init")
+    gen.inits = append(gen.inits, name)
+    return name
+}
+
+// GenPkgInit generates the initialization of the package whose files
+// are files. It runs on an async function when an initializer, an
+// `init` function or `main` can block.
+func (gen *Gen) GenPkgInit(files []*ast.File) string {
+    var out string
+    if len(files) == 0 {
+        return out
+    }
+    scope := gen.Info.Scopes[files[0]].Parent()
+
+    async := false
+    for _, init := range gen.Info.InitOrder {
+        if !gen.InFiles(init.Lhs[0], files) {
+            // the imported packages are checked with the same Info
+            continue
+        }
+        out += gen.GenInitializer(init)
+        stmt := &ast.ExprStmt{X: init.Rhs}
+        async = async || gen.Blocks(&ast.BlockStmt{List: []ast.Stmt{stmt}})
+    }
+
+    calls := gen.inits
+    if main, ok := scope.Lookup("main").(*types.Func); ok {
+        calls = append(calls, JsName(main.Name()))
+    }
+    for _, file := range files {
+        for _, decl := range file.Decls {
+            fun, ok := decl.(*ast.FuncDecl)
+            if ok && fun.Recv == nil && (fun.Name.Name == "init" || fun.Name.Name == "main") {
+                async = async || gen.async[fun.Body]
+            }
+        }
+    }
+    for _, call := range calls {
+        if async {
+            out += "await "
+        }
+        out += call + "();"
+    }
+    gen.inits = nil
+
+    if async {
+        return "(async()=>{" + out + "})();"
+    }
+    return out
+}
+
+// InFiles reports if an object is declared by one of the files, the
+// blank variables do not belong to the package scope.
+func (gen *Gen) InFiles(obj types.Object, files []*ast.File) bool {
+    for _, file := range files {
+        if gen.Info.Scopes[file].Contains(obj.Pos()) {
+            return true
+        }
+    }
+    return false
+}
+
+// GenInitializer assigns the value of a package variable, `var a, b =
+// f()` initializes its variables together.
+func (gen *Gen) GenInitializer(init *types.Initializer) string {
+    gen.AddDepth()
+    defer gen.RemDepth()
+
+    if len(init.Lhs) == 1 {
+        v := init.Lhs[0]
+        if v.Name() == "_" {
+            return gen.GenExpr(init.Rhs) + ";"
+        }
+        return JsName(v.Name()) + "=" + gen.GenValue(init.Rhs, v.Type()) + ";"
+    }
+
+    names := make([]string, len(init.Lhs))
+    for i, v := range init.Lhs {
+        if v.Name() != "_" {
+            names[i] = JsName(v.Name())
+        }
+    }
+    return "[" + strings.Join(names, ",") + "]=" + gen.GenExpr(init.Rhs) + ";"
+}
@@ -0,0 +1,262 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "strconv"
+    "go/types"
+)
+
+// Interface values box the value with the descriptor of its dynamic
+// type, see runtime.js for the representation. The descriptors of the
+// types used by a package are generated before its code.
+
+func IsInterface(t types.Type) bool {
+    if t == nil {
+        return false
+    }
+    if IsTypeParam(t) {
+        // the constraint of a type parameter is not its type
+        return false
+    }
+    _, ok := t.Underlying().(*types.Interface)
+    return ok
+}
+
+// TypeDesc returns the js name of the runtime descriptor of the type,
+// identical types share the same descriptor. The descriptors of types
+// built from type parameters are made where they are used.
+func (gen *Gen) TypeDesc(t types.Type) string {
+    if tp, ok := t.(*types.TypeParam); ok {
+        return TypeParamDesc(tp)
+    }
+    if HasTypeParam(t) {
+        return gen.GenTypeFor(t)
+    }
+    for i, rtype := range gen.rtypes {
+        if types.Identical(rtype, t) {
+            return fmt.Sprintf("$type%d", i)
+        }
+    }
+    gen.rtypes = append(gen.rtypes, t)
+    return fmt.Sprintf("$type%d", len(gen.rtypes) - 1)
+}
+
+// TypeString returns the go name of the type as printed by the panics
+// of the runtime.
+func TypeString(t types.Type) string {
+    return types.TypeString(t, func(pkg *types.Package) string {
+        return pkg.Name()
+    })
+}
+
+// GenTypeDescs generates the descriptors of the types returned by
+// TypeDesc.
+func (gen *Gen) GenTypeDescs() string {
+    var out string
+    // the descriptors can add the descriptors of their type arguments
+    for i := 0; i < len(gen.rtypes); i++ {
+        out += fmt.Sprintf("const $type%d=%s;", i, gen.GenTypeFor(gen.rtypes[i]))
+    }
+    gen.rtypes = nil
+    return out
+}
+
+// GenTypeFor generates the descriptor of a type, see $typeFor.
+func (gen *Gen) GenTypeFor(t types.Type) string {
+    var methods string
+    if !IsInterface(t) {
+        mset := types.NewMethodSet(t)
+        for j := 0; j < mset.Len(); j++ {
+            method := mset.At(j).Obj().(*types.Func)
+            name := method.Name()
+            targs := gen.GenTypeArgs(gen.RecvTypeArgs(mset.At(j)))
+            if gen.IsParamMethod(mset.At(j)) {
+                _, isPtr := t.(*types.Pointer)
+                recv := "this.$val"
+                if !isPtr {
+                    recv = gen.CloneValue(recv, t)
+                }
+                methods += name + ":function(...a){return " + gen.GenMethodFunc(method, isPtr, targs) + "(" + recv + ",...a);}"
+            } else {
+                methods += name + ":function(...a){return this.$val." + name + "(" + joinArgs(targs, "...a") + ");}"
+            }
+            if j < mset.Len() - 1 {
+                methods += ","
+            }
+        }
+    }
+    equal, hash := "null", "null"
+    if types.Comparable(t) || HasTypeParam(t) {
+        equal = "(a,b)=>" + gen.EqualValue("a", "b", t)
+        hash = "(v)=>" + gen.HashValue("v", t)
+    }
+    name := JsString(TypeString(t))
+    if HasTypeParam(t) {
+        name = gen.TypeKey(t, false)
+    }
+    return fmt.Sprintf(
+        "$typeFor(%s,()=>new $Type(%s,{%s},%s,%s,{%s}))",
+        gen.TypeKey(t, true), name, methods, equal, hash, gen.GenTypeProps(t),
+    )
+}
+
+// GenBox converts the js expression value of type t to the interface
+// type to, values that already are interfaces are not boxed again.
+func (gen *Gen) GenBox(value string, t types.Type, to types.Type) string {
+    if !IsInterface(to) || t == nil || IsInterface(t) {
+        return value
+    }
+    if tp, ok := t.(*types.TypeParam); ok {
+        return gen.GenBoxTypeParam(value, tp)
+    }
+    if basic, ok := t.(*types.Basic); ok {
+        if basic.Kind() == types.UntypedNil {
+            return value
+        }
+        t = types.Default(t)
+    }
+    return "$box(" + value + "," + gen.TypeDesc(t) + ")"
+}
+
+// MethodSigs returns a js object of the methods of the method set of a
+// type, the names of the methods are mapped to the keys of their
+// signatures so an interface is only implemented by the methods of the
+// same signatures.
+func (gen *Gen) MethodSigs(t types.Type) string {
+    mset := types.NewMethodSet(t)
+    var out string
+    out += "{"
+    for i := 0; i < mset.Len(); i++ {
+        out += strconv.Quote(mset.At(i).Obj().Name()) + ":" + gen.TypeKey(mset.At(i).Type(), true)
+        if i < mset.Len() - 1 {
+            out += ","
+        }
+    }
+    out += "}"
+    return out
+}
+
+// GenTypeCheck returns a js expression that reports if the dynamic type
+// of the interface value x is t, a nil t checks for the nil interface.
+func (gen *Gen) GenTypeCheck(x string, t types.Type) string {
+    if t == nil {
+        return "(" + x + "===null)"
+    }
+    if IsInterface(t) {
+        return "$implements(" + x + "," + gen.MethodSigs(t) + ")"
+    }
+    if IsTypeParam(t) {
+        return "$isType(" + x + "," + gen.TypeDesc(t) + ")"
+    }
+    return "$hasType(" + x + "," + gen.TypeDesc(t) + ")"
+}
+
+// GenTypeAssertExpr generates `x.(T)` and `v, ok := x.(T)`.
+func (gen *Gen) GenTypeAssertExpr(expr *ast.TypeAssertExpr) string {
+    t := gen.Info.TypeOf(expr.Type)
+
+    gen.AddDepth()
+    defer gen.RemDepth()
+
+    x := gen.GenExpr(expr.X)
+    _, commaOk := gen.Info.TypeOf(expr).(*types.Tuple)
+    if IsInterface(t) {
+        if commaOk {
+            return "$assertIfaceOk(" + x + "," + gen.MethodSigs(t) + ")"
+        }
+        return "$assertIface(" + x + "," + JsString(TypeString(t)) + "," + gen.MethodSigs(t) + ")"
+    }
+    if IsTypeParam(t) {
+        if commaOk {
+            return "$assertTypeOk(" + x + "," + gen.TypeDesc(t) + ")"
+        }
+        return "$assertType(" + x + "," + gen.TypeDesc(t) + ")"
+    }
+    if commaOk {
+        return "$assertOk(" + x + "," + gen.TypeDesc(t) + "," + gen.ZeroValue(t) + ")"
+    }
+    return "$assert(" + x + "," + gen.TypeDesc(t) + ")"
+}
+
+// GenTypeSwitchStmt generates a type switch as a chain of type checks,
+// the chain is wrapped in a js switch so `break` leaves it.
+func (gen *Gen) GenTypeSwitchStmt(expr *ast.TypeSwitchStmt) string {
+    var out string
+    out += "switch (0) {default:"
+    out += gen.GenStmt(expr.Init)
+
+    var subj ast.Expr
+    switch e := expr.Assign.(type) {
+        case *ast.AssignStmt: subj = e.Rhs[0]
+        case *ast.ExprStmt: subj = e.X
+        default: {}
+    }
+    subj = unparen(subj).(*ast.TypeAssertExpr).X
+
+    x := gen.Temp("x")
+    gen.AddDepth()
+    out += "const " + x + "=" + gen.GenExpr(subj) + ";"
+    gen.RemDepth()
+
+    var def *ast.CaseClause
+    var chain string
+    for _, stmt := range expr.Body.List {
+        clause := stmt.(*ast.CaseClause)
+        if clause.List == nil {
+            def = clause
+            continue
+        }
+        var conds string
+        for i, e := range clause.List {
+            var t types.Type
+            if !gen.IsNilIdent(e) {
+                t = gen.Info.TypeOf(e)
+            }
+            conds += gen.GenTypeCheck(x, t)
+            if i < len(clause.List) - 1 {
+                conds += "||"
+            }
+        }
+        if chain != "" {
+            chain += "else "
+        }
+        chain += "if (" + conds + "){" + gen.GenTypeClause(clause, x) + "}"
+    }
+    if def != nil {
+        body := gen.GenTypeClause(def, x)
+        if chain == "" {
+            chain = body
+        } else {
+            chain += "else{" + body + "}"
+        }
+    }
+
+    out += chain
+    out += "}"
+    return out
+}
+
+// GenTypeClause generates the body of a clause of a type switch, the
+// switch variable of the clause is declared from the interface value x.
+func (gen *Gen) GenTypeClause(clause *ast.CaseClause, x string) string {
+    var out string
+    if obj, ok := gen.Info.Implicits[clause]; ok && obj.Name() != "_" {
+        value := x
+        if t := obj.Type(); IsTypeParam(t) {
+            value = "$typeVal(" + x + "," + gen.TypeDesc(t) + ")"
+        } else if !IsInterface(t) {
+            value = gen.CloneValue(x + ".$val", t)
+        }
+        name := gen.DeclVar(obj)
+        out += "let " + name + "=" + value + ";"
+        out += gen.GenPtrDecl(obj, name)
+    }
+    out += gen.GenBlockStmt(&ast.BlockStmt{List: clause.Body})
+    return out
+}
+
+func (gen *Gen) IsNilIdent(expr ast.Expr) bool {
+    _, ok := gen.ObjectOf(expr).(*types.Nil)
+    return ok
+}
@@ -0,0 +1,211 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "go/token"
+    "go/types"
+)
+
+// Labels of loops, switches and selects are js labels. A block with
+// labels targeted by `goto` is generated as a state machine: a js
+// switch on the state inside a loop, where each label starts a case
+// and a goto sets the state and continues the loop.
+
+// target is a statement that `break` or `continue` can leave.
+type target struct {
+    // label is the js label of the statement, it is only generated
+    // when used is true.
+    label string
+    used bool
+    loop bool
+    // name is the go label of the statement.
+    name string
+    // machine is set for the loop of a goto state machine, it is not
+    // a target of the go code.
+    machine bool
+    // rangeFunc is set for a range over a function while its body is
+    // generated, see GenFuncRange.
+    rangeFunc *rangeFunc
+}
+
+// gotoLabel is a label targeted by goto.
+type gotoLabel struct {
+    state string
+    loop string
+    index int
+}
+
+// FindGotos finds the labels targeted by goto statements.
+func (gen *Gen) FindGotos(files []*ast.File) {
+    gen.gotos = map[types.Object]*gotoLabel{}
+    for _, file := range files {
+        ast.Inspect(file, func(node ast.Node) bool {
+            if branch, ok := node.(*ast.BranchStmt); ok && branch.Tok == token.GOTO {
+                gen.gotos[gen.Info.ObjectOf(branch.Label)] = nil
+            }
+            return true
+        })
+    }
+}
+
+func (gen *Gen) IsGotoTarget(stmt ast.Stmt) bool {
+    labeled, ok := stmt.(*ast.LabeledStmt)
+    if !ok {
+        return false
+    }
+    _, ok = gen.gotos[gen.Info.ObjectOf(labeled.Label)]
+    return ok
+}
+
+// GenTarget generates a statement that break or continue can leave,
+// the statement is labeled if a break or continue crossing a goto
+// state machine targets it.
+func (gen *Gen) GenTarget(loop bool, generate func() string) string {
+    t := &target{label: gen.Temp("L"), loop: loop, name: gen.label}
+    gen.label = ""
+    gen.targets = append(gen.targets, t)
+    out := generate()
+    gen.targets = gen.targets[:len(gen.targets)-1]
+    if !t.used {
+        return out
+    }
+    if loop {
+        return t.label + ":" + out
+    }
+    return t.label + ":{" + out + "}"
+}
+
+// GenUnlabeledBranch generates a `break` or `continue` without label,
+// the js one would target the loop of a goto state machine so the
+// target is labeled.
+func (gen *Gen) GenUnlabeledBranch(tok token.Token) string {
+    crossed := false
+    for i := len(gen.targets) - 1; i >= 0; i-- {
+        t := gen.targets[i]
+        if t.machine {
+            crossed = true
+            continue
+        }
+        if tok == token.CONTINUE && !t.loop {
+            continue
+        }
+        if !crossed {
+            break
+        }
+        t.used = true
+        return tok.String() + " " + t.label + ";"
+    }
+    return tok.String() + ";"
+}
+
+// BranchTarget returns the index on gen.targets of the statement left
+// by a `break` or `continue`.
+func (gen *Gen) BranchTarget(stmt *ast.BranchStmt) int {
+    for i := len(gen.targets) - 1; i >= 0; i-- {
+        t := gen.targets[i]
+        if stmt.Label != nil {
+            if t.name == stmt.Label.Name {
+                return i
+            }
+        } else if !t.machine && (t.loop || stmt.Tok == token.BREAK) {
+            return i
+        }
+    }
+    return -1
+}
+
+// GenBranch generates a `break` or `continue`, the ones that leave the
+// body of a range over a function return from it instead.
+func (gen *Gen) GenBranch(stmt *ast.BranchStmt) string {
+    i := gen.BranchTarget(stmt)
+    for j := len(gen.targets) - 1; j >= 0 && j >= i; j-- {
+        rf := gen.targets[j].rangeFunc
+        if rf == nil {
+            continue
+        }
+        if j != i {
+            return rf.Exit(stmt, "")
+        }
+        if stmt.Tok == token.CONTINUE {
+            return "return true;"
+        }
+        return "return false;"
+    }
+    if stmt.Label != nil {
+        return stmt.Tok.String() + " " + stmt.Label.Name + ";"
+    }
+    return gen.GenUnlabeledBranch(stmt.Tok)
+}
+
+func (gen *Gen) GenLabeledStmt(stmt *ast.LabeledStmt) string {
+    switch stmt.Stmt.(type) {
+        case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt: {
+            gen.label = stmt.Label.Name
+        }
+        default: {}
+    }
+    out := gen.GenStmt(stmt.Stmt)
+    switch stmt.Stmt.(type) {
+        case *ast.ForStmt, *ast.RangeStmt: return stmt.Label.Name + ":" + out
+        case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt: {
+            return stmt.Label.Name + ":{" + out + "}"
+        }
+        default: {
+            // only goto can target the label
+            return out
+        }
+    }
+}
+
+// GenGotoBlock generates a list of statements with labels targeted by
+// goto, see the top of the file. The variables declared by the list
+// are declared before the machine so they survive the jumps.
+func (gen *Gen) GenGotoBlock(stmts []ast.Stmt) string {
+    state, loop := gen.Temp("s"), gen.Temp("goto")
+
+    index := 0
+    for _, stmt := range stmts {
+        if gen.IsGotoTarget(stmt) {
+            index++
+            label := gen.Info.ObjectOf(stmt.(*ast.LabeledStmt).Label)
+            gen.gotos[label] = &gotoLabel{state: state, loop: loop, index: index}
+        }
+    }
+
+    hoisted := gen.hoisted
+    gen.hoisted = &[]string{}
+    gen.targets = append(gen.targets, &target{machine: true})
+
+    var cases string
+    cases += "case 0:"
+    index = 0
+    for _, stmt := range stmts {
+        if gen.IsGotoTarget(stmt) {
+            index++
+            cases += fmt.Sprintf("case %d:", index)
+        }
+        cases += gen.GenStmt(stmt)
+    }
+
+    gen.targets = gen.targets[:len(gen.targets)-1]
+    names := *gen.hoisted
+    gen.hoisted = hoisted
+
+    var out string
+    for _, name := range names {
+        out += "let " + name + ";"
+    }
+    out += "let " + state + "=0;"
+    out += loop + ": for (;;) {switch (" + state + ") {" + cases + "}break;}"
+    return out
+}
+
+// GenGoto generates `goto label`.
+func (gen *Gen) GenGoto(label *ast.Ident) string {
+    g := gen.gotos[gen.Info.ObjectOf(label)]
+    if g == nil {
+        return gen.Errorf(label, "goto %s is not supported", label.Name)
+    }
+    return fmt.Sprintf("{%s=%d;continue %s;}", g.state, g.index, g.loop)
+}
@@ -0,0 +1,77 @@
+package gen
+
+import (
+    "go/ast"
+    "go/types"
+)
+
+// Go maps are lowered onto the `$Map` type of the runtime, see
+// runtime.js for the representation.
+
+func (gen *Gen) IsMap(expr ast.Expr) bool {
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return false
+    }
+    _, ok := CoreType(t).(*types.Map)
+    return ok
+}
+
+func (gen *Gen) MapType(expr ast.Expr) *types.Map {
+    return CoreType(gen.Info.TypeOf(expr)).(*types.Map)
+}
+
+func (gen *Gen) GenMapIndex(expr *ast.IndexExpr) string {
+    m := gen.MapType(expr.X)
+
+    gen.AddDepth()
+
+    args := gen.GenExpr(expr.X) + "," + gen.GenValue(expr.Index, m.Key()) + "," + gen.ZeroValue(m.Elem())
+
+    gen.RemDepth()
+
+    if _, ok := gen.Info.TypeOf(expr).(*types.Tuple); ok {
+        // v, ok := m[k]
+        return "$mapLookup(" + args + ")"
+    }
+    return "$mapGet(" + args + ")"
+}
+
+func (gen *Gen) GenMapStore(expr *ast.IndexExpr, value string) string {
+    gen.AddDepth()
+    m := gen.MapType(expr.X)
+    out := "$mapSet(" + gen.GenExpr(expr.X) + "," + gen.GenValue(expr.Index, m.Key()) + "," + value + ")"
+    gen.RemDepth()
+    return out
+}
+
+func (gen *Gen) GenMapLit(expr *ast.CompositeLit) string {
+    m := gen.MapType(expr)
+
+    gen.AddDepth()
+
+    var entries string
+    for i, elt := range expr.Elts {
+        kv := elt.(*ast.KeyValueExpr)
+        entries += "[" + gen.GenValue(kv.Key, m.Key()) + "," + gen.GenValue(kv.Value, m.Elem()) + "]"
+        if i < len(expr.Elts) - 1 {
+            entries += ","
+        }
+    }
+
+    gen.RemDepth()
+
+    return "$makeMap(" + gen.MapHash(m.Key()) + ",[" + entries + "])"
+}
+
+func (gen *Gen) GenMapRange(expr *ast.RangeStmt) string {
+    key, val, prologue := gen.GenRangeVars(expr, gen.MapType(expr.X).Key(), gen.MapType(expr.X).Elem())
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    gen.RemDepth()
+
+    body := prologue + gen.GenBlockStmt(expr.Body)
+
+    return "for (" + gen.LoopDecl() + "[" + key + "," + val + "] of $mapRange(" + subj + ")) {" + body + "}"
+}
@@ -0,0 +1,188 @@
+package gen
+
+import (
+    "go/ast"
+    "strconv"
+    "go/types"
+)
+
+// Named types that are not structs are represented by the js values of
+// their underlying type, a number has no prototype to hold the methods
+// so they are properties of a js function named like the type and take
+// the receiver as their first argument: `Mode.String(m)`.
+
+// RecvIsParam reports if the methods of the named type take their
+// receiver as the first argument, see the top of the file.
+func (gen *Gen) RecvIsParam(t *types.Named) bool {
+    if t == nil || gen.IsJsType(t) {
+        return false
+    }
+    switch t.Underlying().(type) {
+        case *types.Struct, *types.Interface: return false
+        default: return true
+    }
+}
+
+// IsRecvParam reports if the selector is a method value of a named type
+// whose methods take their receiver as the first argument.
+func (gen *Gen) IsRecvParam(expr *ast.SelectorExpr) bool {
+    sel := gen.Info.Selections[expr]
+    if sel == nil || sel.Kind() != types.MethodVal {
+        return false
+    }
+    return gen.IsParamMethod(sel)
+}
+
+// MethodRecv returns the receiver of the declaration of a method.
+func MethodRecv(method types.Object) *types.Var {
+    return method.Type().(*types.Signature).Recv()
+}
+
+// GenNamedType generates the declaration of a named type that is not a
+// struct or an interface.
+func (gen *Gen) GenNamedType(name string) string {
+    return "function " + name + "(){}"
+}
+
+// GenStructType generates the constructor of a struct type and the
+// methods that copy its values, tparams are the type parameters of a
+// generic type.
+func (gen *Gen) GenStructType(name string, t *types.Struct, tparams *types.TypeParamList) string {
+    var params, fields string
+    for i := 0; i < t.NumFields(); i++ {
+        field := t.Field(i).Name()
+        params += JsName(field)
+        if i < t.NumFields() - 1 {
+            params += ","
+        }
+        fields += "this." + field + "=" + JsName(field) + ";"
+    }
+    return "function " + name + "(" + params + "){" + fields + "}" + gen.GenStructMethods(name, t, tparams)
+}
+
+// GenRecvArg generates the receiver passed to a method that takes it as
+// the first argument, `x.M()` takes the address of x or dereferences it
+// like go does. Value receivers are copied.
+func (gen *Gen) GenRecvArg(expr *ast.SelectorExpr) string {
+    if name, ok := gen.evaluated[expr.X]; ok {
+        // the receiver of a deferred call
+        return name
+    }
+    recv := MethodRecv(gen.Info.Selections[expr].Obj()).Type()
+    _, recvPtr := recv.(*types.Pointer)
+    _, xPtr := gen.Info.TypeOf(expr.X).Underlying().(*types.Pointer)
+    switch {
+        case recvPtr && !xPtr: return gen.GenAddress(expr.X)
+        case !recvPtr && xPtr: {
+            gen.AddDepth()
+            defer gen.RemDepth()
+            return gen.CloneValue(gen.GenExpr(expr.X) + ".$get()", recv)
+        }
+        case !recvPtr: return gen.GenValue(expr.X, nil)
+        default: return gen.GenExpr(expr.X)
+    }
+}
+
+// GenMethodFunc generates the js function of the method of a type that
+// takes its receiver as the first argument, ptr is the type of the
+// receiver the function is called with and targs are the descriptors
+// of the type arguments of a generic type.
+func (gen *Gen) GenMethodFunc(method *types.Func, ptr bool, targs string) string {
+    name := gen.TypeName(RecvType(MethodRecv(method).Type())) + "." + method.Name()
+    recv := "r"
+    if _, isPtr := MethodRecv(method).Type().(*types.Pointer); !isPtr && ptr {
+        recv = "r.$get()"
+    }
+    if recv == "r" && targs == "" {
+        return name
+    }
+    return "((r,...a)=>" + name + "(" + joinArgs(targs, recv, "...a") + "))"
+}
+
+// GenConversion generates the conversion `T(x)`.
+func (gen *Gen) GenConversion(arg ast.Expr, to types.Type) string {
+    from := gen.Info.TypeOf(arg)
+    switch {
+        case IsInterface(to): return gen.GenValue(arg, to)
+        case IsTypeParam(to): {
+            x := gen.GenExpr(arg)
+            if IsBigInt(from) {
+                x = "Number(" + x + ")"
+            }
+            if IsNumeric(from) {
+                return gen.TypeDesc(to) + ".conv(" + x + ")"
+            }
+            return x
+        }
+        case IsTypeParam(from) && IsNumeric(to): {
+            // the argument can be a number or a BigInt
+            x := "Number(" + gen.GenExpr(arg) + ")"
+            return gen.GenNumericConversion(x, types.Typ[types.Float64], to)
+        }
+        case IsStringConversion(from, to): return gen.GenStringConversion(arg, to)
+        case IsNumeric(from) && IsNumeric(to): {
+            return gen.GenNumericConversion(gen.GenExpr(arg), from, to)
+        }
+        case gen.IsStruct(to) && !types.Identical(from, to): {
+            return gen.GenStructConversion(gen.GenExpr(arg), to)
+        }
+        default: return gen.GenValue(arg, nil)
+    }
+}
+
+// IsNumeric reports if the type is an integer, float or complex type.
+func IsNumeric(t types.Type) bool {
+    basic := basicOf(t)
+    return basic != nil && basic.Info() & types.IsNumeric != 0
+}
+
+// GenNumericConversion converts the js number or BigInt x, floats are
+// truncated towards zero and integers are wrapped to the size of the
+// new type.
+func (gen *Gen) GenNumericConversion(x string, from types.Type, to types.Type) string {
+    if basicOf(to).Info() & types.IsFloat != 0 {
+        if IsBigInt(from) {
+            x = "Number(" + x + ")"
+        }
+        return WrapInt(x, to)
+    }
+    if basicOf(from).Info() & types.IsFloat != 0 {
+        x = "Math.trunc(" + x + ")"
+    }
+    switch {
+        case IsBigInt(to) && !IsBigInt(from): return WrapInt("BigInt(" + x + ")", to)
+        case IsBigInt(from) && IsSized(to): {
+            // wrap before the BigInt loses precision
+            bits := types.SizesFor("gc", "amd64").Sizeof(to) * 8
+            wrap := "BigInt.asIntN("
+            if basicOf(to).Info() & types.IsUnsigned != 0 {
+                wrap = "BigInt.asUintN("
+            }
+            return "Number(" + wrap + strconv.Itoa(int(bits)) + "," + x + "))"
+        }
+        case IsBigInt(from) && !IsBigInt(to): return "Number(" + x + ")"
+        default: return WrapInt(x, to)
+    }
+}
+
+// GenStructConversion converts the js value x of a struct type to the
+// struct type to, the fields are copied to a new value of it.
+func (gen *Gen) GenStructConversion(x string, to types.Type) string {
+    st := to.Underlying().(*types.Struct)
+    named, isNamed := to.(*types.Named)
+    var fields string
+    for i := 0; i < st.NumFields(); i++ {
+        field := st.Field(i)
+        if !isNamed {
+            fields += field.Name() + ":"
+        }
+        fields += gen.CloneValue("$s." + field.Name(), field.Type())
+        if i < st.NumFields() - 1 {
+            fields += ","
+        }
+    }
+    if isNamed {
+        return "(($s)=>new " + gen.TypeName(named) + "(" + fields + "))(" + x + ")"
+    }
+    return "(($s)=>({" + fields + "}))(" + x + ")"
+}
@@ -0,0 +1,183 @@
+package gen
+
+import (
+    "go/token"
+    "go/types"
+)
+
+// Numeric operations are lowered by the static type of the operands,
+// see runtime.js for the representation of each integer type.
+
+func basicOf(t types.Type) *types.Basic {
+    if t == nil {
+        return nil
+    }
+    basic, _ := t.Underlying().(*types.Basic)
+    return basic
+}
+
+// IsInteger reports if the type is an integer type.
+func IsInteger(t types.Type) bool {
+    basic := basicOf(t)
+    return basic != nil && basic.Info() & types.IsInteger != 0
+}
+
+// IsComplex reports if the type is a complex type, complex numbers are
+// not supported.
+func IsComplex(t types.Type) bool {
+    basic := basicOf(t)
+    return basic != nil && basic.Info() & types.IsComplex != 0
+}
+
+// IsBigInt reports if values of the type are js BigInt values.
+func IsBigInt(t types.Type) bool {
+    basic := basicOf(t)
+    return basic != nil && (basic.Kind() == types.Int64 || basic.Kind() == types.Uint64)
+}
+
+// WrapInt returns a js expression that wraps the value of expr to the
+// width of the integer type.
+func WrapInt(expr string, t types.Type) string {
+    basic := basicOf(t)
+    if basic == nil {
+        return expr
+    }
+    switch basic.Kind() {
+        case types.Int8: return "(" + expr + "<<24>>24)"
+        case types.Int16: return "(" + expr + "<<16>>16)"
+        case types.Int32: return "(" + expr + "|0)"
+        case types.Uint8: return "(" + expr + "&255)"
+        case types.Uint16: return "(" + expr + "&65535)"
+        case types.Uint32: return "(" + expr + ">>>0)"
+        case types.Int64: return "BigInt.asIntN(64," + expr + ")"
+        case types.Uint64: return "BigInt.asUintN(64," + expr + ")"
+        case types.Uint, types.Uintptr: return "$wrapUint(" + expr + ")"
+        case types.Float32: return "Math.fround(" + expr + ")"
+        default: return expr
+    }
+}
+
+// IsSized reports if the type is an integer type of 32 bits or less
+// that is wrapped after every operation.
+func IsSized(t types.Type) bool {
+    basic := basicOf(t)
+    if basic == nil {
+        return false
+    }
+    switch basic.Kind() {
+        case types.Int8, types.Int16, types.Int32, types.Uint8, types.Uint16, types.Uint32: return true
+        default: return false
+    }
+}
+
+// IsPlainArith reports if `x op y` on values of the type has the same
+// result in js and go.
+func IsPlainArith(op token.Token, t types.Type) bool {
+    if IsTypeParam(t) {
+        return false
+    }
+    basic := basicOf(t)
+    if basic == nil {
+        return true
+    }
+    if basic.Info() & types.IsInteger == 0 {
+        return basic.Kind() != types.Float32
+    }
+    if IsSized(t) || IsBigInt(t) || basic.Info() & types.IsUnsigned != 0 {
+        // uint and uintptr wrap below zero
+        return false
+    }
+    switch op {
+        case token.ADD, token.SUB, token.MUL: return true
+        default: return false
+    }
+}
+
+// GenArith generates the arithmetic operation `x op y` on the js
+// expressions x and y of type t, count is the type of y for shifts.
+func (gen *Gen) GenArith(op token.Token, x string, y string, t types.Type, count types.Type) string {
+    if IsPlainArith(op, t) {
+        return "(" + x + op.String() + y + ")"
+    }
+    if tp, ok := t.(*types.TypeParam); ok {
+        return GenTypeArith(op, x, y, tp)
+    }
+
+    if IsBigInt(t) {
+        var out string
+        switch op {
+            case token.QUO: out = "$idiv64(" + x + "," + y + ")"
+            case token.REM: out = "$imod64(" + x + "," + y + ")"
+            case token.SHL: out = "(" + x + "<<BigInt($shiftCount(" + y + ")))"
+            case token.SHR: out = "(" + x + ">>BigInt($shiftCount(" + y + ")))"
+            case token.AND_NOT: out = "(" + x + "&~" + y + ")"
+            default: out = "(" + x + op.String() + y + ")"
+        }
+        return WrapInt(out, t)
+    }
+
+    if !IsInteger(t) {
+        // float32
+        return WrapInt("(" + x + op.String() + y + ")", t)
+    }
+
+    var out string
+    switch op {
+        case token.QUO: out = "$idiv(" + x + "," + y + ")"
+        case token.REM: out = "$imod(" + x + "," + y + ")"
+        case token.SHL: out = "$shl(" + x + "," + y + ")"
+        case token.SHR: out = "$shr(" + x + "," + y + ")"
+        case token.MUL: {
+            if IsSized(t) {
+                out = "Math.imul(" + x + "," + y + ")"
+            } else {
+                out = "(" + x + "*" + y + ")"
+            }
+        }
+        case token.AND, token.OR, token.XOR, token.AND_NOT: {
+            if IsSized(t) {
+                if op == token.AND_NOT {
+                    out = "(" + x + "&~" + y + ")"
+                } else {
+                    out = "(" + x + op.String() + y + ")"
+                }
+            } else {
+                names := map[token.Token]string{
+                    token.AND: "$and",
+                    token.OR: "$or",
+                    token.XOR: "$xor",
+                    token.AND_NOT: "$andNot",
+                }
+                out = names[op] + "(" + x + "," + y + ")"
+            }
+        }
+        default: out = "(" + x + op.String() + y + ")"
+    }
+    return WrapInt(out, t)
+}
+
+// GenNegate generates the unary `-x` and `^x` on the js expression x.
+func (gen *Gen) GenNegate(op token.Token, x string, t types.Type) string {
+    if tp, ok := t.(*types.TypeParam); ok {
+        if op == token.SUB {
+            return TypeParamDesc(tp) + ".ops.neg(" + x + ")"
+        }
+        return TypeParamDesc(tp) + ".ops.not(" + x + ")"
+    }
+    if op == token.SUB {
+        return WrapInt("(-" + x + ")", t)
+    }
+    // bitwise complement
+    if IsSized(t) || IsBigInt(t) {
+        return WrapInt("(~" + x + ")", t)
+    }
+    if basicOf(t).Info() & types.IsUnsigned != 0 {
+        return "Number(BigInt.asUintN(64,~BigInt(" + x + ")))"
+    }
+    return "(-" + x + "-1)"
+}
+
+// GenBigIntConstant returns the js BigInt literal of a constant.
+func (gen *Gen) GenBigIntConstant(tv types.TypeAndValue) string {
+    return tv.Value.ExactString() + "n"
+}
@@ -0,0 +1,298 @@
+package gen
+
+import (
+    "go/ast"
+    "go/token"
+    "go/types"
+)
+
+// Pointers and struct values, see runtime.js for the representation.
+
+// IsStruct reports if the values of the type are struct objects that
+// are copied on assignment.
+func (gen *Gen) IsStruct(t types.Type) bool {
+    if t == nil || gen.IsJsType(t) {
+        return false
+    }
+    _, ok := t.Underlying().(*types.Struct)
+    return ok
+}
+
+// RecvType returns the named type of a method receiver.
+func RecvType(t types.Type) *types.Named {
+    if ptr, ok := t.(*types.Pointer); ok {
+        t = ptr.Elem()
+    }
+    named, _ := t.(*types.Named)
+    return named
+}
+
+// GenAddress generates `&expr`.
+func (gen *Gen) GenAddress(expr ast.Expr) string {
+    if t := gen.Info.TypeOf(expr); gen.IsStruct(t) || gen.IsJsType(t) {
+        // the struct object is the pointer
+        return gen.GenExpr(expr)
+    }
+
+    gen.AddDepth()
+    defer gen.RemDepth()
+
+    switch e := unparen(expr).(type) {
+        case *ast.CompositeLit: {
+            return "$newPtr(" + gen.GenExpr(e) + ")"
+        }
+        case *ast.StarExpr: {
+            return gen.GenExpr(e.X)
+        }
+        case *ast.Ident: {
+            if ptr, ok := gen.ptrs[gen.Info.ObjectOf(e)]; ok {
+                return ptr
+            }
+        }
+        case *ast.SelectorExpr: {
+            if sel := gen.Info.Selections[e]; sel != nil && sel.Kind() == types.FieldVal {
+                return "$fieldPtr(" + gen.GenFieldOwner(e) + ",\"" + e.Sel.Name + "\")"
+            }
+        }
+        case *ast.IndexExpr: {
+            args := gen.GenIndexed(e.X) + "," + gen.GenExpr(e.Index)
+            if gen.IsSlice(e.X) {
+                return "$slicePtr(" + args + ")"
+            }
+            return "$arrayPtr(" + args + ")"
+        }
+        default: {}
+    }
+
+    name := gen.GenExpr(expr)
+    v := gen.Temp("v")
+    return "new $Ptr(()=>" + name + ",(" + v + ")=>{" + name + "=" + v + ";})"
+}
+
+// FindAddressed finds the variables of the files whose address is
+// taken by `&x` or by calling a method with a pointer receiver, every
+// variable gets a single `$Ptr` so its pointers are equal.
+func (gen *Gen) FindAddressed(files []*ast.File) {
+    gen.addressed = map[types.Object]bool{}
+    gen.ptrs = map[types.Object]string{}
+    add := func(expr ast.Expr) {
+        ident, ok := unparen(expr).(*ast.Ident)
+        if !ok {
+            return
+        }
+        v, ok := gen.Info.Uses[ident].(*types.Var)
+        if ok && !v.IsField() && !gen.IsStruct(v.Type()) && !gen.IsJsType(v.Type()) {
+            gen.addressed[v] = true
+        }
+    }
+    for _, file := range files {
+        ast.Inspect(file, func(node ast.Node) bool {
+            switch e := node.(type) {
+                case *ast.UnaryExpr: {
+                    if e.Op == token.AND {
+                        add(e.X)
+                    }
+                }
+                case *ast.SelectorExpr: {
+                    sel := gen.Info.Selections[e]
+                    if sel == nil || sel.Kind() != types.MethodVal || sel.Indirect() {
+                        break
+                    }
+                    _, recvPtr := MethodRecv(sel.Obj()).Type().(*types.Pointer)
+                    _, xPtr := gen.Info.TypeOf(e.X).Underlying().(*types.Pointer)
+                    if recvPtr && !xPtr {
+                        add(e.X)
+                    }
+                }
+                default: {}
+            }
+            return true
+        })
+    }
+}
+
+// GenPtrDecls declares the pointers of the addressed variables declared
+// by the identifiers, see GenPtrDecl.
+func (gen *Gen) GenPtrDecls(exprs []ast.Expr) string {
+    var out string
+    for _, expr := range exprs {
+        ident, ok := expr.(*ast.Ident)
+        if !ok || isBlank(ident) || gen.Info.Defs[ident] == nil {
+            continue
+        }
+        out += gen.GenPtrDecl(gen.Info.Defs[ident], gen.GenIdent(ident))
+    }
+    return out
+}
+
+// GenPtrDecl declares the pointer of an addressed variable next to the
+// variable so each instance of the variable has one pointer. The
+// hoisted variables of a goto block get a new pointer on each `&x`.
+func (gen *Gen) GenPtrDecl(obj types.Object, name string) string {
+    if !gen.addressed[obj] || gen.hoisted != nil {
+        return ""
+    }
+    ptr, v := gen.Temp("p"), gen.Temp("v")
+    gen.ptrs[obj] = ptr
+    return "let " + ptr + "=new $Ptr(()=>" + name + ",(" + v + ")=>{" + name + "=" + v + ";});"
+}
+
+// GenStarExpr generates `*expr`, struct values are copied by GenValue
+// when needed.
+func (gen *Gen) GenStarExpr(expr *ast.StarExpr) string {
+    if t := gen.Info.TypeOf(expr); gen.IsStruct(t) || gen.IsJsType(t) {
+        return gen.GenExpr(expr.X)
+    }
+    return gen.GenExpr(expr.X) + ".$get()"
+}
+
+// GenStructMethods generates the methods that copy the value of a
+// struct type, they take the descriptors of the type arguments of a
+// generic type.
+func (gen *Gen) GenStructMethods(name string, t *types.Struct, tparams *types.TypeParamList) string {
+    var out string
+    targs := GenTypeParams(tparams)
+
+    var fields string
+    for i := 0; i < t.NumFields(); i++ {
+        field := t.Field(i)
+        fields += gen.CloneValue("this." + field.Name(), field.Type())
+        if i < t.NumFields() - 1 {
+            fields += ","
+        }
+    }
+    out += name + ".prototype.$clone=function(" + targs + "){return new " + name + "(" + fields + ");};"
+
+    sets := gen.GenFieldSets("this", "v", t)
+    out += name + ".prototype.$set=function(" + joinArgs("v", targs) + "){" + sets + "};"
+
+    return out
+}
+
+// GenFieldSets generates the statements that copy the fields of the
+// struct value from to the struct object to.
+func (gen *Gen) GenFieldSets(to, from string, t *types.Struct) string {
+    var out string
+    for i := 0; i < t.NumFields(); i++ {
+        field := t.Field(i)
+        out += gen.GenCopyInto(to + "." + field.Name(), from + "." + field.Name(), field.Type()) + ";"
+    }
+    return out
+}
+
+// GenCopyInto generates the copy of the value from to the variable to,
+// nested structs and arrays are updated in place, pointers to them must
+// see the new value.
+func (gen *Gen) GenCopyInto(to, from string, t types.Type) string {
+    if gen.IsStruct(t) {
+        return gen.GenStructSet(to, from, t)
+    }
+    if _, isArray := CoreType(t).(*types.Array); isArray {
+        return gen.GenArraySet(to, from, t)
+    }
+    return to + "=" + gen.CloneValue(from, t)
+}
+
+// GenArraySet generates the update in place of the js array to with
+// the array value from, the pointers to its elements stay valid.
+func (gen *Gen) GenArraySet(to, from string, t types.Type) string {
+    elem := CoreType(t).(*types.Array).Elem()
+    d, s, i := gen.Temp("d"), gen.Temp("s"), gen.Temp("i")
+    set := gen.GenCopyInto(d + "[" + i + "]", s + "[" + i + "]", elem)
+    return "((" + d + "," + s + ")=>{for (let " + i + "=0;" + i + "<" + d + ".length;" + i + "++) {" + set + ";}})(" + to + "," + from + ")"
+}
+
+// GenStructSet generates the update in place of the struct object to
+// with the value from, anonymous structs are js objects without a
+// `$set` method so their fields are copied one by one.
+func (gen *Gen) GenStructSet(to, from string, t types.Type) string {
+    if _, isNamed := t.(*types.Named); isNamed {
+        return to + ".$set(" + joinArgs(from, gen.GenTypeArgs(NamedTypeArgs(t))) + ")"
+    }
+    d, s := gen.Temp("d"), gen.Temp("s")
+    sets := gen.GenFieldSets(d, s, t.Underlying().(*types.Struct))
+    return "((" + d + "," + s + ")=>{" + sets + "})(" + to + "," + from + ")"
+}
+
+// GenSelector generates field selectors and method values, calls to
+// methods are generated by GenCallee.
+func (gen *Gen) GenSelector(expr *ast.SelectorExpr) string {
+    sel := gen.Info.Selections[expr]
+    if sel != nil && sel.Kind() == types.MethodVal && gen.IsRecvParam(expr) {
+        args := joinArgs(gen.GenTypeArgs(gen.RecvTypeArgs(sel)), gen.GenRecvArg(expr))
+        return gen.GenMethodFunc(sel.Obj().(*types.Func), false, "") + ".bind(null," + args + ")"
+    }
+    if sel != nil && sel.Kind() == types.MethodExpr && gen.IsParamMethod(sel) {
+        _, isPtr := sel.Recv().(*types.Pointer)
+        return gen.GenMethodFunc(sel.Obj().(*types.Func), isPtr, gen.GenTypeArgs(gen.RecvTypeArgs(sel)))
+    }
+    if sel != nil && sel.Kind() == types.MethodVal {
+        args := joinArgs(gen.GenMethodRecv(expr), "\"" + expr.Sel.Name + "\"", gen.GenTypeArgs(gen.RecvTypeArgs(sel)))
+        return "$methodVal(" + args + ")"
+    }
+    if sel != nil && sel.Kind() == types.MethodExpr {
+        args := joinArgs(gen.TypeName(RecvType(sel.Recv())), "\"" + expr.Sel.Name + "\"", gen.GenTypeArgs(gen.RecvTypeArgs(sel)))
+        return "$methodExpr(" + args + ")"
+    }
+    if sel != nil && sel.Kind() == types.FieldVal {
+        return gen.GenFieldOwner(expr) + "." + expr.Sel.Name
+    }
+    parent := gen.GenExpr(expr.X)
+    callee := gen.GenExpr(expr.Sel)
+    return parent + "." + callee
+}
+
+// GenMethodRecv generates the receiver of a method value, value
+// receivers are copied when the method value is evaluated. A value
+// receiver reached through pointers is dereferenced before the copy.
+func (gen *Gen) GenMethodRecv(expr *ast.SelectorExpr) string {
+    sel := gen.Info.Selections[expr]
+    if tp, ok := sel.Recv().(*types.TypeParam); ok {
+        // a method of the constraint
+        return gen.GenBoxTypeParam(gen.GenValue(expr.X, nil), tp)
+    }
+    recv := sel.Obj().Type().(*types.Signature).Recv().Type()
+    if _, isPtr := recv.(*types.Pointer); isPtr {
+        return gen.GenExpr(expr.X)
+    }
+    if !sel.Indirect() {
+        return gen.GenValue(expr.X, nil)
+    }
+    owner := gen.GenFieldOwner(expr)
+    t := sel.Recv()
+    for _, i := range sel.Index()[:len(sel.Index()) - 1] {
+        t = structOf(t).Field(i).Type()
+    }
+    if _, isPtr := t.Underlying().(*types.Pointer); isPtr && !gen.IsStruct(recv) && !gen.IsJsType(recv) {
+        owner += ".$get()"
+    }
+    return gen.CloneValue(owner, recv)
+}
+
+// GenCallee generates the function of a call, methods are called on
+// their receiver instead of being bound and the type arguments of a
+// generic function are passed by GenCall.
+func (gen *Gen) GenCallee(expr ast.Expr) string {
+    if _, ok := gen.evaluated[expr]; ok {
+        return gen.GenExpr(expr)
+    }
+    if gen.FuncTypeArgs(expr) != nil {
+        return gen.FuncName(gen.ObjectOf(expr))
+    }
+    if e, ok := unparen(expr).(*ast.SelectorExpr); ok {
+        if gen.IsRecvParam(e) {
+            return gen.GenMethodFunc(gen.Info.Selections[e].Obj().(*types.Func), false, "")
+        }
+        if sel := gen.Info.Selections[e]; sel != nil && sel.Kind() == types.MethodVal {
+            if IsTypeParam(sel.Recv()) {
+                return gen.GenMethodRecv(e) + "." + e.Sel.Name
+            }
+            return gen.GenExpr(e.X) + "." + e.Sel.Name
+        }
+    }
+    if _, ok := expr.(*ast.FuncLit); ok {
+        // func() {...}()
+        return "(" + gen.GenExpr(expr) + ")"
+    }
+    return gen.GenExpr(expr)
+}
@@ -0,0 +1,235 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "strings"
+    "go/token"
+    "go/types"
+)
+
+// Range loops are lowered by the type of the operand, see the Gen*Range
+// functions. The key and the value of an iteration are js variables of
+// the loop that a prologue of the body copies or assigns to the go ones.
+
+func (gen *Gen) GenRangeStmt(expr *ast.RangeStmt) string {
+    if expr.Tok == token.DEFINE {
+        gen.DeclLoopVars([]ast.Expr{expr.Key, expr.Value})
+    }
+    t := gen.Info.TypeOf(expr.X)
+    if ptr, ok := t.Underlying().(*types.Pointer); ok {
+        t = ptr.Elem()
+    }
+    switch u := CoreType(t).(type) {
+        case *types.Map: return gen.GenMapRange(expr)
+        case *types.Slice, *types.Array: return gen.GenSliceRange(expr)
+        case *types.Chan: return gen.GenChanRange(expr)
+        case *types.Signature: return gen.GenFuncRange(expr, u)
+        case *types.Basic: {
+            if u.Info() & types.IsString != 0 {
+                return gen.GenStringRange(expr)
+            }
+            return gen.GenIntRange(expr)
+        }
+        default: {
+            return gen.Errorf(expr.X, "cannot range over %s", TypeString(t))
+        }
+    }
+}
+
+// GenRangeVars generates the js variables iterated for the key and the
+// value of a range loop and the prologue of the body.
+func (gen *Gen) GenRangeVars(expr *ast.RangeStmt, key types.Type, elem types.Type) (string, string, string) {
+    var k, v, prologue string
+    if expr.Key != nil && !isBlank(expr.Key) {
+        k, prologue = gen.GenRangeVar(expr, expr.Key, key)
+    }
+    if expr.Value != nil && !isBlank(expr.Value) {
+        var assign string
+        v, assign = gen.GenRangeVar(expr, expr.Value, elem)
+        prologue += assign
+    }
+    if expr.Tok == token.DEFINE {
+        prologue += gen.GenPtrDecls([]ast.Expr{expr.Key, expr.Value})
+    }
+    return k, v, prologue
+}
+
+// GenRangeVar generates the js variable iterated for the key or value
+// v of type t, values that go copies are copied by the prologue and
+// `for k = range x` assigns the existing k in the prologue.
+func (gen *Gen) GenRangeVar(expr *ast.RangeStmt, v ast.Expr, t types.Type) (string, string) {
+    if expr.Tok == token.ASSIGN {
+        tmp := gen.Temp("v")
+        return tmp, gen.GenRangeAssign(v, tmp, t)
+    }
+    name := gen.GenExpr(v)
+    if gen.NeedsClone(t) {
+        tmp := gen.Temp("v")
+        return tmp, gen.LoopDecl() + name + "=" + gen.CloneValue(tmp, t) + ";"
+    }
+    return name, ""
+}
+
+// GenRangeAssign generates the assignment of the js value of type t to
+// the key or value of `for k, v = range x`.
+func (gen *Gen) GenRangeAssign(lhs ast.Expr, value string, t types.Type) string {
+    value = gen.GenBox(gen.CloneValue(value, t), t, gen.Info.TypeOf(lhs))
+    if gen.IsStore(lhs) {
+        return gen.GenStore(lhs, value) + ";"
+    }
+    gen.AddDepth()
+    out := gen.GenExpr(lhs) + "=" + value + ";"
+    gen.RemDepth()
+    return out
+}
+
+// GenIntRange generates `for i := range n`, the loop counts with its
+// own variable so assigning i does not change the iterations.
+func (gen *Gen) GenIntRange(expr *ast.RangeStmt) string {
+    t := types.Default(gen.Info.TypeOf(expr.X))
+    if expr.Key != nil && !isBlank(expr.Key) {
+        t = gen.Info.TypeOf(expr.Key)
+    }
+    i, n := gen.Temp("i"), gen.Temp("n")
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    gen.RemDepth()
+
+    var prologue string
+    if expr.Key != nil && !isBlank(expr.Key) {
+        if expr.Tok == token.DEFINE {
+            prologue = gen.LoopDecl() + gen.GenExpr(expr.Key) + "=" + i + ";"
+            prologue += gen.GenPtrDecls([]ast.Expr{expr.Key})
+        } else {
+            prologue = gen.GenRangeAssign(expr.Key, i, t)
+        }
+    }
+    body := prologue + gen.GenBlockStmt(expr.Body)
+
+    return fmt.Sprintf(
+        "for (let %s=%s,%s=%s;%s<%s;%s++) {%s}",
+        i, gen.ZeroValue(t), n, subj, i, n, i, body,
+    )
+}
+
+// GenStringRange generates the range over the runes of a string, the
+// key is the byte offset of the rune.
+func (gen *Gen) GenStringRange(expr *ast.RangeStmt) string {
+    key, val, prologue := gen.GenRangeVars(expr, types.Typ[types.Int], types.Typ[types.Rune])
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    gen.RemDepth()
+
+    body := prologue + gen.GenBlockStmt(expr.Body)
+
+    return fmt.Sprintf("for (%s[%s,%s] of $stringRange(%s)) {%s}", gen.LoopDecl(), key, val, subj, body)
+}
+
+// rangeFunc is a range over a function being generated. Its body is a
+// js function passed as the yield function, a `break`, `continue` or
+// `return` that leaves the loop sets exit and returns false, the
+// statement is generated again after the call to run it.
+type rangeFunc struct {
+    exit string
+    // results holds the results of a `return` while the iteration
+    // stops.
+    results string
+    exits []ast.Stmt
+}
+
+// Exit generates the statement that leaves the body of the loop to run
+// stmt, prologue runs first.
+func (rf *rangeFunc) Exit(stmt ast.Stmt, prologue string) string {
+    rf.exits = append(rf.exits, stmt)
+    return fmt.Sprintf("{%s%s=%d;return false;}", prologue, rf.exit, len(rf.exits))
+}
+
+// RangeFunc returns the innermost range over a function of the current
+// function.
+func (gen *Gen) RangeFunc() *rangeFunc {
+    for i := len(gen.targets) - 1; i >= 0; i-- {
+        if gen.targets[i].rangeFunc != nil {
+            return gen.targets[i].rangeFunc
+        }
+    }
+    return nil
+}
+
+// GenFuncRange generates the range over an iterator function `f(yield)`.
+func (gen *Gen) GenFuncRange(expr *ast.RangeStmt, sig *types.Signature) string {
+    if gen.Blocks(expr.Body) {
+        return gen.Errorf(expr, "the body of a range over a function cannot block")
+    }
+    yield := sig.Params().At(0).Type().Underlying().(*types.Signature)
+
+    var params []string
+    var prologue string
+    vars := []ast.Expr{expr.Key, expr.Value}
+    for i, t := range TupleTypes(yield.Params()) {
+        if vars[i] == nil || isBlank(vars[i]) {
+            params = append(params, gen.Temp("_"))
+            continue
+        }
+        param, assign := gen.GenRangeVar(expr, vars[i], t)
+        params = append(params, param)
+        prologue += assign
+    }
+    if expr.Tok == token.DEFINE {
+        prologue += gen.GenPtrDecls(vars)
+    }
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    gen.RemDepth()
+
+    rf := &rangeFunc{exit: gen.Temp("exit"), results: gen.Temp("rv")}
+    t := gen.targets[len(gen.targets)-1]
+    t.rangeFunc = rf
+    body := gen.GenBlockStmt(expr.Body)
+    t.rangeFunc = nil
+
+    var out string
+    out += "{let " + rf.exit + "=0," + rf.results + ";"
+    call := subj + "((" + strings.Join(params, ",") + ")=>{" + prologue + body + "return true;})"
+    if gen.IsAsyncCall(&ast.CallExpr{Fun: expr.X}) {
+        call = "await " + call
+    }
+    out += call + ";"
+    for i, stmt := range rf.exits {
+        out += fmt.Sprintf("if (%s===%d){%s}", rf.exit, i + 1, gen.GenExit(stmt, rf))
+    }
+    out += "}"
+    return out
+}
+
+// GenRangeFuncReturn generates a `return` inside the body of a range
+// over a function, the results are evaluated before leaving it.
+func (gen *Gen) GenRangeFuncReturn(stmt *ast.ReturnStmt, rf *rangeFunc) string {
+    if len(stmt.Results) == 0 {
+        return rf.Exit(stmt, "")
+    }
+    gen.AddDepth()
+    results := rf.results + "=[" + gen.GenValues(stmt.Results, nil) + "];"
+    gen.RemDepth()
+    return rf.Exit(stmt, results)
+}
+
+// GenExit generates a statement that left the body of the range over
+// a function after the iteration stopped.
+func (gen *Gen) GenExit(stmt ast.Stmt, rf *rangeFunc) string {
+    ret, ok := stmt.(*ast.ReturnStmt)
+    if !ok {
+        return gen.GenStmt(stmt)
+    }
+    for i, result := range ret.Results {
+        gen.Evaluated(result, fmt.Sprintf("%s[%d]", rf.results, i))
+    }
+    out := gen.GenReturnStmt(ret)
+    for _, result := range ret.Results {
+        delete(gen.evaluated, result)
+    }
+    return out
+}
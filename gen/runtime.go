@@ -0,0 +1,9 @@
+package gen
+
+import _ "embed"
+
+// Runtime is the javascript code the generated packages depend on,
+// it must be emitted before any generated code.
+//
+//go:embed runtime.js
+var Runtime string
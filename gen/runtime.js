@@ -0,0 +1,1038 @@
+// Elma runtime
+//   Helpers used by the generated code to keep go semantics where
+//   javascript differs.
+
+// maps:
+//   A go map is backed by a js Map from the hashed key to a `[key, value]`
+//   entry. `hash` is null when the keys can be compared by js itself
+//   (numbers, strings, booleans and pointers) otherwise it converts the
+//   key into a string that is equal for keys that are equal in go.
+//   The nil map is `null`.
+function $Map(hash) {
+    this.hash = hash;
+    this.entries = new Map();
+}
+
+$Map.prototype.keyOf = function (key) {
+    return this.hash === null ? key : this.hash(key);
+};
+
+function $makeMap(hash, entries) {
+    const m = new $Map(hash);
+    for (const [key, value] of entries) {
+        m.entries.set(m.keyOf(key), [key, value]);
+    }
+    return m;
+}
+
+function $mapGet(m, key, zero) {
+    if (m === null) {
+        return zero;
+    }
+    const entry = m.entries.get(m.keyOf(key));
+    return entry === undefined ? zero : entry[1];
+}
+
+function $mapLookup(m, key, zero) {
+    if (m === null) {
+        return [zero, false];
+    }
+    const entry = m.entries.get(m.keyOf(key));
+    return entry === undefined ? [zero, false] : [entry[1], true];
+}
+
+function $mapSet(m, key, value) {
+    if (m === null) {
+        throw $runtimeError("assignment to entry in nil map");
+    }
+    const hash = m.keyOf(key);
+    const entry = m.entries.get(hash);
+    if (entry === undefined) {
+        m.entries.set(hash, [key, value]);
+    } else {
+        entry[1] = value;
+    }
+}
+
+function $mapDelete(m, key) {
+    if (m !== null) {
+        m.entries.delete(m.keyOf(key));
+    }
+}
+
+function $mapLen(m) {
+    return m === null ? 0 : m.entries.size;
+}
+
+// $mapRange iterates the `[key, value]` entries, entries deleted during
+// the iteration are not visited.
+function $mapRange(m) {
+    return m === null ? [] : m.entries.values();
+}
+
+// $id gives every object an unique number, it is used to hash
+// values compared by identity such as pointers.
+const $ids = new WeakMap();
+let $nextId = 1;
+
+function $id(obj) {
+    if (obj === null || (typeof obj !== "object" && typeof obj !== "function")) {
+        return obj;
+    }
+    let id = $ids.get(obj);
+    if (id === undefined) {
+        id = $nextId++;
+        $ids.set(obj, id);
+    }
+    return "#" + id;
+}
+
+// slices:
+//   A slice is a window over a js array shared with the slices created
+//   from it. The fields are named so `length` keeps working for js code
+//   that expects an array. The nil slice is `null`, arrays are plain js
+//   arrays copied on assignment.
+function $Slice(array, offset, length, capacity) {
+    this.array = array;
+    this.offset = offset;
+    this.length = length;
+    this.capacity = capacity;
+}
+
+function $indexPanic(index, length) {
+    throw $runtimeError("runtime error: index out of range [" + index + "] with length " + length);
+}
+
+function $sliceOf(array) {
+    return new $Slice(array, 0, array.length, array.length);
+}
+
+function $makeSlice(length, capacity, zero) {
+    if (capacity === undefined) {
+        capacity = length;
+    }
+    if (length < 0 || length > capacity) {
+        throw $runtimeError("runtime error: makeslice: len out of range");
+    }
+    const array = new Array(capacity);
+    for (let i = 0; i < capacity; i++) {
+        array[i] = zero();
+    }
+    return new $Slice(array, 0, length, capacity);
+}
+
+function $sliceLen(s) {
+    return s === null ? 0 : s.length;
+}
+
+function $sliceCap(s) {
+    return s === null ? 0 : s.capacity;
+}
+
+function $sliceGet(s, i) {
+    const length = $sliceLen(s);
+    if (i < 0 || i >= length) {
+        $indexPanic(i, length);
+    }
+    return s.array[s.offset + i];
+}
+
+function $sliceSet(s, i, value) {
+    const length = $sliceLen(s);
+    if (i < 0 || i >= length) {
+        $indexPanic(i, length);
+    }
+    s.array[s.offset + i] = value;
+}
+
+function $arrayGet(a, i) {
+    if (i < 0 || i >= a.length) {
+        $indexPanic(i, a.length);
+    }
+    return a[i];
+}
+
+function $arraySet(a, i, value) {
+    if (i < 0 || i >= a.length) {
+        $indexPanic(i, a.length);
+    }
+    a[i] = value;
+}
+
+// $slice implements `s[low:high:max]` for slices and arrays, missing
+// indexes are undefined.
+function $slice(s, low, high, max) {
+    if (Array.isArray(s)) {
+        s = $sliceOf(s);
+    }
+    const length = $sliceLen(s);
+    const capacity = $sliceCap(s);
+    if (low === undefined) {
+        low = 0;
+    }
+    if (high === undefined) {
+        high = length;
+    }
+    if (max === undefined) {
+        max = capacity;
+    }
+    if (low < 0 || high < low || max < high || max > capacity) {
+        throw $runtimeError("runtime error: slice bounds out of range [" + low + ":" + high + ":" + max + "] with capacity " + capacity);
+    }
+    if (s === null) {
+        return null;
+    }
+    return new $Slice(s.array, s.offset + low, high - low, max - low);
+}
+
+// $append adds the js array values to the slice, when the capacity is
+// not enough a new backing array is allocated and the free space is
+// filled with zero values.
+function $append(s, values, zero) {
+    const length = $sliceLen(s);
+    const capacity = $sliceCap(s);
+    if (values.length === 0) {
+        return s;
+    }
+    if (length + values.length <= capacity) {
+        for (let i = 0; i < values.length; i++) {
+            s.array[s.offset + length + i] = values[i];
+        }
+        return new $Slice(s.array, s.offset, length + values.length, capacity);
+    }
+    const grown = Math.max(capacity * 2, length + values.length);
+    const array = new Array(grown);
+    for (let i = 0; i < length; i++) {
+        array[i] = s.array[s.offset + i];
+    }
+    for (let i = 0; i < values.length; i++) {
+        array[length + i] = values[i];
+    }
+    for (let i = length + values.length; i < grown; i++) {
+        array[i] = zero();
+    }
+    return new $Slice(array, 0, length + values.length, grown);
+}
+
+function $appendSlice(s, t, zero) {
+    return $append(s, $toArray(t), zero);
+}
+
+function $copy(dst, src) {
+    const values = $toArray(src);
+    const n = Math.min($sliceLen(dst), values.length);
+    for (let i = 0; i < n; i++) {
+        dst.array[dst.offset + i] = values[i];
+    }
+    return n;
+}
+
+// $toArray returns a new js array with the elements of the slice.
+function $toArray(s) {
+    if (s === null) {
+        return [];
+    }
+    return s.array.slice(s.offset, s.offset + s.length);
+}
+
+function* $sliceRange(s) {
+    if (s === null) {
+        return;
+    }
+    const { array, offset, length } = s;
+    for (let i = 0; i < length; i++) {
+        yield [i, array[offset + i]];
+    }
+}
+
+function* $arrayRange(a) {
+    for (let i = 0; i < a.length; i++) {
+        yield [i, a[i]];
+    }
+}
+
+// $variadic packs the values of a call `f(g())` when f is variadic,
+// params is the number of parameters of f.
+function $variadic(values, params) {
+    const fixed = values.slice(0, params - 1);
+    fixed.push($sliceOf(values.slice(params - 1)));
+    return fixed;
+}
+
+// pointers:
+//   A pointer to a struct is the struct object itself, the generated
+//   `$clone` and `$set` methods of the struct copy its value. Pointers
+//   to any other variable are `$Ptr` values that read and write the
+//   variable through closures. The nil pointer is `null`.
+function $Ptr(get, set) {
+    this.$get = get;
+    this.$set = set;
+}
+
+function $newPtr(value) {
+    return new $Ptr(() => value, (v) => { value = v; });
+}
+
+// $ptrs holds the pointers to the fields and elements of each object,
+// `&x.f` returns the same pointer every time so pointers compare and
+// hash like in go. The pointers to variables are declared with them.
+const $ptrs = new WeakMap();
+
+function $ptrTo(obj, key) {
+    let ptrs = $ptrs.get(obj);
+    if (ptrs === undefined) {
+        ptrs = new Map();
+        $ptrs.set(obj, ptrs);
+    }
+    let ptr = ptrs.get(key);
+    if (ptr === undefined) {
+        ptr = new $Ptr(() => obj[key], (v) => { obj[key] = v; });
+        ptrs.set(key, ptr);
+    }
+    return ptr;
+}
+
+function $fieldPtr(obj, name) {
+    return $ptrTo(obj, name);
+}
+
+function $slicePtr(s, i) {
+    $sliceGet(s, i);
+    return $ptrTo(s.array, s.offset + i);
+}
+
+function $arrayPtr(a, i) {
+    $arrayGet(a, i);
+    return $ptrTo(a, i);
+}
+
+// $methodVal implements method values `x.M`, the method is bound to
+// the receiver when the expression is evaluated. targs are the type
+// arguments of a generic type, see generics.
+function $methodVal(recv, name, ...targs) {
+    return recv[name].bind(recv, ...targs);
+}
+
+// $methodExpr implements method expressions `T.M`, the receiver is
+// the first argument.
+function $methodExpr(type, name, ...targs) {
+    return (recv, ...args) => type.prototype[name].call(recv, ...targs, ...args);
+}
+
+// strings:
+//   A go string is a js string of the bytes of its utf-8 encoding, every
+//   char code is a byte. `length`, indexing, `substring` and comparisons
+//   work on bytes like in go. Strings are converted to js strings when
+//   they are passed to a binding and back when a binding returns one.
+
+// $decodeRune decodes the utf-8 rune at the offset i of s, it returns
+// the rune and its width. Invalid encodings decode as U+FFFD of width
+// 1 like in go.
+function $decodeRune(s, i) {
+    const c0 = s.charCodeAt(i);
+    if (c0 < 0x80) {
+        return [c0, 1];
+    }
+    const cont = (j) => {
+        const c = s.charCodeAt(i + j);
+        return (c & 0xc0) === 0x80 ? c & 0x3f : -1;
+    };
+    if (c0 >= 0xc2 && c0 < 0xe0) {
+        const c1 = cont(1);
+        if (c1 >= 0) {
+            return [(c0 & 0x1f) << 6 | c1, 2];
+        }
+    } else if (c0 >= 0xe0 && c0 < 0xf0) {
+        const c1 = cont(1), c2 = cont(2);
+        const r = (c0 & 0x0f) << 12 | c1 << 6 | c2;
+        if (c1 >= 0 && c2 >= 0 && r >= 0x800 && (r < 0xd800 || r > 0xdfff)) {
+            return [r, 3];
+        }
+    } else if (c0 >= 0xf0 && c0 < 0xf5) {
+        const c1 = cont(1), c2 = cont(2), c3 = cont(3);
+        const r = (c0 & 0x07) << 18 | c1 << 12 | c2 << 6 | c3;
+        if (c1 >= 0 && c2 >= 0 && c3 >= 0 && r >= 0x10000 && r <= 0x10ffff) {
+            return [r, 4];
+        }
+    }
+    return [0xfffd, 1];
+}
+
+// $encodeRune returns the utf-8 encoding of a rune, invalid runes are
+// encoded as U+FFFD.
+function $encodeRune(r) {
+    if (r < 0 || r > 0x10ffff || (r >= 0xd800 && r <= 0xdfff)) {
+        r = 0xfffd;
+    }
+    if (r < 0x80) {
+        return String.fromCharCode(r);
+    }
+    if (r < 0x800) {
+        return String.fromCharCode(0xc0 | r >> 6, 0x80 | r & 0x3f);
+    }
+    if (r < 0x10000) {
+        return String.fromCharCode(0xe0 | r >> 12, 0x80 | r >> 6 & 0x3f, 0x80 | r & 0x3f);
+    }
+    return String.fromCharCode(0xf0 | r >> 18, 0x80 | r >> 12 & 0x3f, 0x80 | r >> 6 & 0x3f, 0x80 | r & 0x3f);
+}
+
+// $runeToString implements `string(r)` for integers, r can be a BigInt.
+function $runeToString(r) {
+    if (typeof r === "bigint") {
+        r = r < 0n || r > 0x10ffffn ? -1 : Number(r);
+    }
+    return $encodeRune(r);
+}
+
+function $toJsString(s) {
+    let out = "";
+    for (let i = 0; i < s.length;) {
+        const [r, width] = $decodeRune(s, i);
+        out += String.fromCodePoint(r);
+        i += width;
+    }
+    return out;
+}
+
+// $fromJsString encodes a js string, lone surrogates are encoded as
+// U+FFFD.
+function $fromJsString(s) {
+    let out = "";
+    for (const c of s) {
+        out += $encodeRune(c.codePointAt(0));
+    }
+    return out;
+}
+
+function $stringGet(s, i) {
+    if (i < 0 || i >= s.length) {
+        $indexPanic(i, s.length);
+    }
+    return s.charCodeAt(i);
+}
+
+// $stringSlice implements `s[low:high]`, missing indexes are undefined.
+function $stringSlice(s, low, high) {
+    if (low === undefined) {
+        low = 0;
+    }
+    if (high === undefined) {
+        high = s.length;
+    }
+    if (low < 0 || high < low || high > s.length) {
+        throw $runtimeError("runtime error: slice bounds out of range [" + low + ":" + high + "] with length " + s.length);
+    }
+    return s.substring(low, high);
+}
+
+function $stringToBytes(s) {
+    const bytes = new Array(s.length);
+    for (let i = 0; i < s.length; i++) {
+        bytes[i] = s.charCodeAt(i);
+    }
+    return $sliceOf(bytes);
+}
+
+function $bytesToString(b) {
+    let out = "";
+    const bytes = $toArray(b);
+    for (let i = 0; i < bytes.length; i += 4096) {
+        out += String.fromCharCode(...bytes.slice(i, i + 4096));
+    }
+    return out;
+}
+
+function $stringToRunes(s) {
+    const runes = [];
+    for (const [, r] of $stringRange(s)) {
+        runes.push(r);
+    }
+    return $sliceOf(runes);
+}
+
+function $runesToString(r) {
+    let out = "";
+    for (const rune of $toArray(r)) {
+        out += $encodeRune(rune);
+    }
+    return out;
+}
+
+// $stringRange iterates the `[offset, rune]` pairs of a string.
+function* $stringRange(s) {
+    for (let i = 0; i < s.length;) {
+        const [r, width] = $decodeRune(s, i);
+        yield [i, r];
+        i += width;
+    }
+}
+
+// integers:
+//   int, uint and uintptr are js numbers exact up to 2^53, the sized
+//   integers up to 32 bits are js numbers wrapped after every operation
+//   and int64/uint64 are BigInt values wrapped to 64 bits.
+function $divPanic() {
+    throw $runtimeError("runtime error: integer divide by zero");
+}
+
+function $idiv(x, y) {
+    if (y === 0) {
+        $divPanic();
+    }
+    return Math.trunc(x / y);
+}
+
+function $imod(x, y) {
+    if (y === 0) {
+        $divPanic();
+    }
+    return x % y;
+}
+
+function $idiv64(x, y) {
+    if (y === 0n) {
+        $divPanic();
+    }
+    return x / y;
+}
+
+function $imod64(x, y) {
+    if (y === 0n) {
+        $divPanic();
+    }
+    return x % y;
+}
+
+function $shiftCount(y) {
+    y = Number(y);
+    if (y < 0) {
+        throw $runtimeError("runtime error: negative shift amount");
+    }
+    return y;
+}
+
+// shifts of numbers are done with floats so they do not truncate to 32
+// bits, the result is wrapped by the caller.
+function $shl(x, y) {
+    return x * Math.pow(2, $shiftCount(y));
+}
+
+function $shr(x, y) {
+    return Math.floor(x / Math.pow(2, $shiftCount(y)));
+}
+
+function $isInt32(x) {
+    return (x | 0) === x;
+}
+
+// $wrapUint wraps the result of an operation on uint values, they are
+// numbers so the values above 2^53 are not exact.
+function $wrapUint(x) {
+    if (x >= 0 && x < 18446744073709551616) {
+        return x;
+    }
+    return Number(BigInt.asUintN(64, BigInt(x)));
+}
+
+// bitwise operations on numbers bigger than 32 bits go through BigInt.
+function $and(x, y) {
+    return $isInt32(x) && $isInt32(y) ? x & y : Number(BigInt(x) & BigInt(y));
+}
+
+function $or(x, y) {
+    return $isInt32(x) && $isInt32(y) ? x | y : Number(BigInt(x) | BigInt(y));
+}
+
+function $xor(x, y) {
+    return $isInt32(x) && $isInt32(y) ? x ^ y : Number(BigInt(x) ^ BigInt(y));
+}
+
+function $andNot(x, y) {
+    return $isInt32(x) && $isInt32(y) ? x & ~y : Number(BigInt(x) & ~BigInt(y));
+}
+
+// interfaces:
+//   A non nil interface value is a box holding the value and the
+//   descriptor of its dynamic type, the box has the methods of the type
+//   forwarding to the value. The nil interface is `null`.
+function $Type(name, methods, equal, hash, props) {
+    const type = this;
+    this.name = name;
+    this.equal = equal;
+    this.hash = hash;
+    this.key = name;
+    this.zero = () => null;
+    this.clone = (v) => v;
+    this.conv = (v) => v;
+    this.mapHash = null;
+    this.ops = null;
+    this.iface = false;
+    this.sigs = {};
+    Object.assign(this, props);
+    this.box = function (value) {
+        this.$val = value;
+    };
+    this.box.prototype.$type = type;
+    for (const method in methods) {
+        this.box.prototype[method] = methods[method];
+    }
+}
+
+function $box(value, type) {
+    return new type.box(value);
+}
+
+// $unbox returns the value of an interface for a binding, strings are
+// converted to js strings.
+function $unbox(x) {
+    if (x === null) {
+        return null;
+    }
+    switch (typeof x.$val) {
+        case "string": return $toJsString(x.$val);
+        case "bigint": return Number(x.$val);
+        default: return x.$val;
+    }
+}
+
+function $typeName(x) {
+    return x === null ? "nil" : x.$type.name;
+}
+
+function $hasType(x, type) {
+    return x !== null && x.$type === type;
+}
+
+// $implements reports if the dynamic type of x has all the methods,
+// methods maps their names to the keys of their signatures like the
+// `sigs` of the descriptors.
+function $implements(x, methods) {
+    if (x === null) {
+        return false;
+    }
+    for (const method in methods) {
+        if (x.$type.sigs[method] !== methods[method]) {
+            return false;
+        }
+    }
+    return true;
+}
+
+function $assert(x, type) {
+    if (x === null || x.$type !== type) {
+        throw $runtimeError("interface conversion: interface is " + $typeName(x) + ", not " + type.name);
+    }
+    return x.$val;
+}
+
+function $assertOk(x, type, zero) {
+    if (x === null || x.$type !== type) {
+        return [zero, false];
+    }
+    return [x.$val, true];
+}
+
+function $assertIface(x, name, methods) {
+    if (!$implements(x, methods)) {
+        throw $runtimeError("interface conversion: " + $typeName(x) + " is not " + name);
+    }
+    return x;
+}
+
+function $assertIfaceOk(x, methods) {
+    return $implements(x, methods) ? [x, true] : [null, false];
+}
+
+function $ifaceEq(x, y) {
+    if (x === null || y === null) {
+        return x === y;
+    }
+    if (x.$type !== y.$type) {
+        return false;
+    }
+    if (x.$type.equal === null) {
+        throw $runtimeError("runtime error: comparing uncomparable type " + x.$type.name);
+    }
+    return x.$type.equal(x.$val, y.$val);
+}
+
+function $ifaceHash(x) {
+    if (x === null) {
+        return null;
+    }
+    if (x.$type.hash === null) {
+        throw $runtimeError("runtime error: hash of unhashable type " + x.$type.name);
+    }
+    return [x.$type.name, x.$type.hash(x.$val)];
+}
+
+// panics:
+//   A panic is thrown as a `$Panic` holding the interface value given
+//   to panic. The runtime errors are panics of a `runtime.Error` whose
+//   message is the go one, js errors thrown by the generated code are
+//   converted to runtime errors when a deferred call sees them.
+function $Panic(value) {
+    this.value = value;
+    this.recovered = false;
+    this.message = "panic: " + $toJsString($panicString(value));
+    this.stack = this.message + "\n" + new Error().stack.split("\n").slice(2).join("\n");
+}
+
+$Panic.prototype = Object.create(Error.prototype);
+$Panic.prototype.name = "panic";
+
+// generics:
+//   Generic functions and the methods of generic types take the
+//   descriptors of their type arguments as their first arguments. The
+//   props of a descriptor implement what depends on the type: key
+//   identifies it, zero, clone and conv make values of it, ops are its
+//   arithmetic operators, mapHash hashes the keys of maps of it, sigs
+//   maps the names of its methods to the keys of their signatures and
+//   iface marks interface types. The descriptors of types
+//   built from type parameters are made when the code runs, $typeFor
+//   shares them with the other descriptors of the same type.
+const $types = new Map();
+
+function $typeFor(key, make) {
+    let type = $types.get(key);
+    if (type === undefined) {
+        type = make();
+        $types.set(key, type);
+    }
+    return type;
+}
+
+// $boxAs converts a value of a type parameter to an interface, values
+// of interface types already are interfaces.
+function $boxAs(value, type) {
+    return type.iface ? value : new type.box(value);
+}
+
+// $isType reports if the dynamic type of x is the type argument.
+function $isType(x, type) {
+    return type.iface ? $implements(x, type.sigs) : $hasType(x, type);
+}
+
+// $typeVal returns the value of x as a value of the type argument.
+function $typeVal(x, type) {
+    return type.iface ? x : type.clone(x.$val);
+}
+
+function $assertType(x, type) {
+    return type.iface ? $assertIface(x, type.name, type.sigs) : $assert(x, type);
+}
+
+function $assertTypeOk(x, type) {
+    return type.iface ? $assertIfaceOk(x, type.sigs) : $assertOk(x, type, type.zero());
+}
+
+const $runtimeErrorType = new $Type("runtime.Error", {
+    Error: function () {
+        return this.$val;
+    },
+}, (a, b) => a === b, (v) => v, {sigs: {Error: "func() string"}});
+
+function $runtimeError(message) {
+    return new $Panic($box(message, $runtimeErrorType));
+}
+
+function $panic(value) {
+    throw new $Panic(value);
+}
+
+function $panicString(value) {
+    if (value === null) {
+        return "nil";
+    }
+    if (typeof value.Error === "function") {
+        return value.Error();
+    }
+    if (typeof value.String === "function") {
+        return value.String();
+    }
+    switch (typeof value.$val) {
+        case "string": case "number": case "boolean": case "bigint": {
+            return String(value.$val);
+        }
+        default: return "(" + value.$type.name + ")";
+    }
+}
+
+function $toPanic(e) {
+    if (e instanceof $Panic) {
+        return e;
+    }
+    if (e instanceof TypeError) {
+        return $runtimeError("runtime error: invalid memory address or nil pointer dereference");
+    }
+    return $runtimeError(String(e.message));
+}
+
+// $runDefers runs the deferred calls of a function in LIFO order, a
+// deferred call that panics replaces the current panic. Each deferred
+// call gets the current panic and passes it to the function it calls,
+// the functions that call `recover` take it as their last argument so
+// a `recover` of any other function gets undefined. It returns the
+// panic of the function, the caller throws it again if it was not
+// recovered.
+function $runDefers(defers, panic) {
+    while (defers.length > 0) {
+        const call = defers.pop();
+        try {
+            call(panic);
+        } catch (e) {
+            panic = $toPanic(e);
+        }
+    }
+    return panic;
+}
+
+function $recover(panic) {
+    if (panic == null || panic.recovered) {
+        return null;
+    }
+    panic.recovered = true;
+    return panic.value;
+}
+
+async function $runDefersAsync(defers, panic) {
+    while (defers.length > 0) {
+        const call = defers.pop();
+        try {
+            await call(panic);
+        } catch (e) {
+            panic = $toPanic(e);
+        }
+    }
+    return panic;
+}
+
+// goroutines:
+//   A goroutine is a js async function, the functions that can block
+//   are async and awaited by their callers so a blocked goroutine lets
+//   the others run. Goroutines are scheduled on the microtask queue.
+function $go(f) {
+    Promise.resolve().then(f);
+}
+
+// $block never resumes, it blocks on a nil channel.
+function $block() {
+    return new Promise(() => {});
+}
+
+// channels:
+//   A channel has a buffer of `capacity` values and the queues of the
+//   goroutines blocked sending and receiving on it. A waiting entry is
+//   skipped once its `select` was resolved by another channel. The nil
+//   channel is `null`.
+function $Chan(capacity) {
+    this.capacity = capacity;
+    this.buffer = [];
+    this.closed = false;
+    this.sendq = [];
+    this.recvq = [];
+}
+
+function $makeChan(capacity) {
+    if (capacity < 0) {
+        throw $runtimeError("makechan: size out of range");
+    }
+    return new $Chan(capacity);
+}
+
+function $chanLen(ch) {
+    return ch === null ? 0 : ch.buffer.length;
+}
+
+function $chanCap(ch) {
+    return ch === null ? 0 : ch.capacity;
+}
+
+// $dequeue removes the first entry of the queue whose select is still
+// waiting.
+function $dequeue(queue) {
+    while (queue.length > 0) {
+        const entry = queue.shift();
+        if (entry.select === null || !entry.select.done) {
+            if (entry.select !== null) {
+                entry.select.done = true;
+            }
+            return entry;
+        }
+    }
+    return null;
+}
+
+// $trySend sends the value if it does not block.
+function $trySend(ch, value) {
+    if (ch.closed) {
+        throw $runtimeError("send on closed channel");
+    }
+    const receiver = $dequeue(ch.recvq);
+    if (receiver !== null) {
+        receiver.resolve([value, true]);
+        return true;
+    }
+    if (ch.buffer.length < ch.capacity) {
+        ch.buffer.push(value);
+        return true;
+    }
+    return false;
+}
+
+// $tryRecv returns the received `[value, ok]` if it does not block.
+function $tryRecv(ch, zero) {
+    if (ch.buffer.length > 0) {
+        const value = ch.buffer.shift();
+        const sender = $dequeue(ch.sendq);
+        if (sender !== null) {
+            ch.buffer.push(sender.value);
+            sender.resolve();
+        }
+        return [value, true];
+    }
+    const sender = $dequeue(ch.sendq);
+    if (sender !== null) {
+        sender.resolve();
+        return [sender.value, true];
+    }
+    if (ch.closed) {
+        return [zero, false];
+    }
+    return null;
+}
+
+async function $send(ch, value) {
+    if (ch === null) {
+        await $block();
+    }
+    if ($trySend(ch, value)) {
+        return;
+    }
+    await new Promise((resolve, reject) => {
+        ch.sendq.push({value: value, resolve: resolve, reject: reject, select: null});
+    });
+}
+
+async function $recv(ch, zero) {
+    if (ch === null) {
+        await $block();
+    }
+    const received = $tryRecv(ch, zero);
+    if (received !== null) {
+        return received;
+    }
+    return new Promise((resolve) => {
+        ch.recvq.push({zero: zero, resolve: resolve, select: null});
+    });
+}
+
+function $close(ch) {
+    if (ch === null) {
+        throw $runtimeError("close of nil channel");
+    }
+    if (ch.closed) {
+        throw $runtimeError("close of closed channel");
+    }
+    ch.closed = true;
+    for (let receiver; (receiver = $dequeue(ch.recvq)) !== null;) {
+        receiver.resolve([receiver.zero, false]);
+    }
+    for (let sender; (sender = $dequeue(ch.sendq)) !== null;) {
+        sender.reject($runtimeError("send on closed channel"));
+    }
+}
+
+// $select runs one of the ready cases, a case is `[ch, zero]` to
+// receive or `[ch, value, true]` to send. It returns the index of the
+// case and the received `[value, ok]`, the index is -1 when no case is
+// ready and there is a default case.
+async function $select(cases, hasDefault) {
+    const order = cases.map((_, i) => i).filter((i) => cases[i][0] !== null);
+    for (let i = order.length - 1; i > 0; i--) {
+        const j = Math.floor(Math.random() * (i + 1));
+        [order[i], order[j]] = [order[j], order[i]];
+    }
+    for (const i of order) {
+        const [ch, value, isSend] = cases[i];
+        if (isSend) {
+            if ($trySend(ch, value)) {
+                return [i, null];
+            }
+        } else {
+            const received = $tryRecv(ch, value);
+            if (received !== null) {
+                return [i, received];
+            }
+        }
+    }
+    if (hasDefault) {
+        return [-1, null];
+    }
+    if (order.length === 0) {
+        await $block();
+    }
+    return new Promise((resolve, reject) => {
+        const select = {done: false};
+        for (const i of order) {
+            const [ch, value, isSend] = cases[i];
+            if (isSend) {
+                ch.sendq.push({value: value, resolve: () => resolve([i, null]), reject: reject, select: select});
+            } else {
+                ch.recvq.push({zero: value, resolve: (received) => resolve([i, received]), select: select});
+            }
+        }
+    });
+}
+
+// sync:
+//   The types of the sync package.
+function $Mutex() {
+    this.locked = false;
+    this.waiters = [];
+}
+
+$Mutex.prototype.lock = async function () {
+    if (!this.locked) {
+        this.locked = true;
+        return;
+    }
+    // the unlocking goroutine hands the lock over
+    await new Promise((resolve) => this.waiters.push(resolve));
+};
+
+$Mutex.prototype.unlock = function () {
+    if (!this.locked) {
+        throw $runtimeError("fatal error: sync: unlock of unlocked mutex");
+    }
+    if (this.waiters.length > 0) {
+        this.waiters.shift()();
+    } else {
+        this.locked = false;
+    }
+};
+
+function $WaitGroup() {
+    this.counter = 0;
+    this.waiters = [];
+}
+
+$WaitGroup.prototype.add = function (delta) {
+    this.counter += delta;
+    if (this.counter < 0) {
+        throw $runtimeError("sync: negative WaitGroup counter");
+    }
+    if (this.counter === 0) {
+        for (const resolve of this.waiters) {
+            resolve();
+        }
+        this.waiters = [];
+    }
+};
+
+$WaitGroup.prototype.wait = async function () {
+    if (this.counter === 0) {
+        return;
+    }
+    await new Promise((resolve) => this.waiters.push(resolve));
+};
@@ -0,0 +1,179 @@
+package gen
+
+import (
+    "go/ast"
+    "strings"
+    "go/token"
+    "go/types"
+)
+
+// Go blocks are js blocks and `:=` declares with `let`, but the scope
+// of a js `let` starts at the top of its block while the scope of a go
+// variable starts after its declaration. A local variable that hides
+// an outer declaration is renamed so the code before it still sees the
+// outer one.
+
+// jsNames are the js reserved words and the globals used by the
+// generated code and the runtime, go names that collide with them are
+// renamed by JsName.
+var jsNames = map[string]bool{
+    "arguments": true, "await": true, "break": true, "case": true,
+    "catch": true, "class": true, "const": true, "continue": true,
+    "debugger": true, "default": true, "delete": true, "do": true,
+    "else": true, "enum": true, "eval": true, "export": true,
+    "extends": true, "false": true, "finally": true, "for": true,
+    "function": true, "if": true, "implements": true, "import": true,
+    "in": true, "instanceof": true, "interface": true, "let": true,
+    "new": true, "null": true, "package": true, "private": true,
+    "protected": true, "public": true, "return": true, "static": true,
+    "super": true, "switch": true, "this": true, "throw": true,
+    "true": true, "try": true, "typeof": true, "var": true,
+    "void": true, "while": true, "with": true, "yield": true,
+    "undefined": true, "NaN": true, "Infinity": true,
+    "Array": true, "BigInt": true, "Error": true, "JSON": true,
+    "Map": true, "Math": true, "Number": true, "Object": true,
+    "Promise": true, "String": true, "Symbol": true, "TypeError": true,
+    "WeakMap": true, "queueMicrotask": true, "console": true,
+    "document": true, "globalThis": true, "setInterval": true,
+    "clearInterval": true, "setTimeout": true,
+}
+
+// JsName returns the js name of a go variable, function or type.
+func JsName(name string) string {
+    if jsNames[name] {
+        return name + "$"
+    }
+    return name
+}
+
+// Shadows reports if a local variable hides a declaration of an outer
+// scope, the builtins of go do not exist in js.
+func Shadows(obj types.Object) bool {
+    scope := obj.Parent()
+    if scope == nil || obj.Pkg() == nil || scope == obj.Pkg().Scope() {
+        return false
+    }
+    _, outer := scope.Parent().LookupParent(obj.Name(), obj.Pos())
+    return outer != nil && outer.Parent() != types.Universe
+}
+
+// DeclVar returns the js name of a local variable being declared, see
+// the top of the file. The shared variables of loops are js `var`s so
+// they always get a new name.
+func (gen *Gen) DeclVar(obj types.Object) string {
+    if Shadows(obj) || gen.loopVars && gen.SharedLoopVars {
+        gen.Binds[obj] = gen.Temp(obj.Name())
+        return gen.Binds[obj]
+    }
+    return JsName(obj.Name())
+}
+
+// DeclIdents names the variables declared by the identifiers and
+// returns them, the identifiers that are not declarations are skipped.
+func (gen *Gen) DeclIdents(exprs []ast.Expr) []ast.Expr {
+    var out []ast.Expr
+    for _, expr := range exprs {
+        ident, ok := expr.(*ast.Ident)
+        if !ok || isBlank(ident) || gen.Info.Defs[ident] == nil {
+            continue
+        }
+        if IsComplex(gen.Info.Defs[ident].Type()) {
+            gen.Errorf(ident, "complex numbers are not supported")
+        }
+        gen.DeclVar(gen.Info.Defs[ident])
+        out = append(out, ident)
+    }
+    return out
+}
+
+// GenDefineStmt generates `a, b := x, y`, the new variables are
+// declared and the redeclared ones are assigned.
+func (gen *Gen) GenDefineStmt(expr *ast.AssignStmt) string {
+    news := gen.DeclIdents(expr.Lhs)
+
+    assign := *expr
+    assign.Tok = token.ASSIGN
+
+    redeclared := false
+    for _, lhs := range expr.Lhs {
+        if !isBlank(lhs) && gen.Info.Defs[lhs.(*ast.Ident)] == nil {
+            redeclared = true
+        }
+    }
+    var ptrs string
+    if !gen.loopVars {
+        // the variables of a `for` loop have their pointers declared
+        // by each iteration, see GenForStmt
+        ptrs = gen.GenPtrDecls(news)
+    }
+    if !redeclared {
+        return gen.GenLet(news) + gen.GenAssignStmt(&assign) + ptrs
+    }
+
+    var out string
+    if let := gen.GenLet(news); let != "" {
+        var names []string
+        for _, name := range news {
+            names = append(names, gen.GenExpr(name))
+        }
+        out += let + strings.Join(names, ",") + ";"
+    }
+    return out + gen.GenAssignStmt(&assign) + ptrs
+}
+
+// GenScoped generates a statement with an init statement, the
+// variables of the init statement are only visible to the statement.
+func (gen *Gen) GenScoped(init ast.Stmt, generate func() string) string {
+    if init == nil {
+        return generate()
+    }
+    return "{" + gen.GenStmt(init) + generate() + "}"
+}
+
+// Loops declare their variables with a js `let` so each iteration has
+// its own variables like in go 1.22, closures created by an iteration
+// see the values of that iteration. With SharedLoopVars they are
+// declared with `var` instead.
+
+// LoopDecl returns the js declaration of the variables of a loop.
+func (gen *Gen) LoopDecl() string {
+    if gen.SharedLoopVars {
+        return "var "
+    }
+    return "let "
+}
+
+// DeclLoopVars names the variables declared by a loop, see DeclIdents.
+func (gen *Gen) DeclLoopVars(exprs []ast.Expr) []ast.Expr {
+    loopVars := gen.loopVars
+    gen.loopVars = true
+    defer func() { gen.loopVars = loopVars }()
+    return gen.DeclIdents(exprs)
+}
+
+// GenLoopInit generates the init statement of a `for` loop.
+func (gen *Gen) GenLoopInit(init ast.Stmt) string {
+    hoisted, loopVars := gen.hoisted, gen.loopVars
+    gen.hoisted, gen.loopVars = nil, true
+    defer func() { gen.hoisted, gen.loopVars = hoisted, loopVars }()
+    return gen.GenStmt(init)
+}
+
+// GenIterationCopies copies the values declared by the init statement
+// of a `for` loop before the post statement of each iteration, js only
+// copies the variables so the values that go copies would be shared.
+func (gen *Gen) GenIterationCopies(init ast.Stmt) []string {
+    assign, ok := init.(*ast.AssignStmt)
+    if !ok || assign.Tok != token.DEFINE || gen.SharedLoopVars {
+        return nil
+    }
+    var out []string
+    for _, lhs := range assign.Lhs {
+        ident := lhs.(*ast.Ident)
+        if obj := gen.Info.Defs[ident]; obj != nil && gen.NeedsClone(obj.Type()) {
+            name := gen.GenIdent(ident)
+            out = append(out, name + "=" + gen.CloneValue(name, obj.Type()))
+        }
+    }
+    return out
+}
@@ -0,0 +1,217 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "go/types"
+    "go/constant"
+)
+
+// Slices are lowered onto the `$Slice` type of the runtime and arrays
+// onto js arrays, see runtime.js for the representation.
+
+func (gen *Gen) IsSlice(expr ast.Expr) bool {
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return false
+    }
+    _, ok := CoreType(t).(*types.Slice)
+    return ok
+}
+
+func (gen *Gen) IsArray(expr ast.Expr) bool {
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return false
+    }
+    _, ok := CoreType(t).(*types.Array)
+    return ok
+}
+
+// IsArrayPtr reports if expr is a pointer to an array, indexing it
+// indexes the array.
+func (gen *Gen) IsArrayPtr(expr ast.Expr) bool {
+    t := gen.Info.TypeOf(expr)
+    if t == nil {
+        return false
+    }
+    ptr, ok := t.Underlying().(*types.Pointer)
+    if !ok {
+        return false
+    }
+    _, ok = ptr.Elem().Underlying().(*types.Array)
+    return ok
+}
+
+// GenIndexed generates the slice or array of an index expression.
+func (gen *Gen) GenIndexed(expr ast.Expr) string {
+    if gen.IsArrayPtr(expr) {
+        return gen.GenExpr(expr) + ".$get()"
+    }
+    return gen.GenExpr(expr)
+}
+
+// ElemType returns the type of the elements of a slice or array.
+func ElemType(t types.Type) types.Type {
+    switch u := CoreType(t).(type) {
+        case *types.Slice: return u.Elem()
+        case *types.Array: return u.Elem()
+        default: return nil
+    }
+}
+
+func (gen *Gen) GenSliceIndex(expr *ast.IndexExpr) string {
+    gen.AddDepth()
+    args := gen.GenIndexed(expr.X) + "," + gen.GenExpr(expr.Index)
+    gen.RemDepth()
+    if gen.IsSlice(expr.X) {
+        return "$sliceGet(" + args + ")"
+    }
+    return "$arrayGet(" + args + ")"
+}
+
+func (gen *Gen) GenSliceStore(expr *ast.IndexExpr, value string) string {
+    gen.AddDepth()
+    args := gen.GenIndexed(expr.X) + "," + gen.GenExpr(expr.Index) + "," + value
+    gen.RemDepth()
+    if gen.IsSlice(expr.X) {
+        return "$sliceSet(" + args + ")"
+    }
+    return "$arraySet(" + args + ")"
+}
+
+func (gen *Gen) GenSliceExpr(expr *ast.SliceExpr) string {
+    if gen.IsString(expr.X) {
+        return gen.GenStringSlice(expr)
+    }
+    gen.AddDepth()
+
+    var out string
+    out += "$slice("
+    out += gen.GenExpr(expr.X)
+    for _, index := range []ast.Expr{expr.Low, expr.High, expr.Max} {
+        out += ","
+        if index != nil {
+            out += gen.GenExpr(index)
+        } else {
+            out += "undefined"
+        }
+    }
+    out += ")"
+
+    gen.RemDepth()
+    return out
+}
+
+// GenArrayLit generates the elements of a slice or array literal as a
+// js array, indexes without an element are set to the zero value.
+func (gen *Gen) GenArrayLit(expr *ast.CompositeLit) string {
+    t := gen.Info.TypeOf(expr)
+    elem := ElemType(t)
+
+    values := map[int64]string{}
+    var length int64
+    var index int64
+
+    gen.AddDepth()
+
+    for _, elt := range expr.Elts {
+        if kv, ok := elt.(*ast.KeyValueExpr); ok {
+            index, _ = constant.Int64Val(gen.Info.Types[kv.Key].Value)
+            elt = kv.Value
+        }
+        values[index] = gen.GenValue(elt, elem)
+        index++
+        if index > length {
+            length = index
+        }
+    }
+
+    gen.RemDepth()
+
+    if array, ok := t.Underlying().(*types.Array); ok {
+        length = array.Len()
+    }
+
+    var out string
+    out += "["
+    for i := int64(0); i < length; i++ {
+        if value, ok := values[i]; ok {
+            out += value
+        } else {
+            out += gen.ZeroValue(elem)
+        }
+        if i < length - 1 {
+            out += ","
+        }
+    }
+    out += "]"
+    return out
+}
+
+func (gen *Gen) GenSliceLit(expr *ast.CompositeLit) string {
+    return "$sliceOf(" + gen.GenArrayLit(expr) + ")"
+}
+
+// ZeroFunc returns a js function that creates zero values of the type,
+// it is used by the runtime to fill new slice elements.
+func (gen *Gen) ZeroFunc(t types.Type) string {
+    return "()=>(" + gen.ZeroValue(t) + ")"
+}
+
+func (gen *Gen) GenSliceRange(expr *ast.RangeStmt) string {
+    t := gen.Info.TypeOf(expr.X)
+    ptr, isPtr := t.Underlying().(*types.Pointer)
+    if isPtr {
+        t = ptr.Elem()
+    }
+    key, val, prologue := gen.GenRangeVars(expr, types.Typ[types.Int], ElemType(t))
+
+    gen.AddDepth()
+    subj := gen.GenExpr(expr.X)
+    if isPtr {
+        subj += ".$get()"
+    } else if gen.IsArray(expr.X) && val != "" {
+        // the range is over a copy of the array
+        subj = gen.CloneValue(subj, t)
+    }
+    gen.RemDepth()
+
+    body := prologue + gen.GenBlockStmt(expr.Body)
+
+    iter := "$sliceRange"
+    if _, isArray := CoreType(t).(*types.Array); isArray {
+        iter = "$arrayRange"
+    }
+    return fmt.Sprintf("for (%s[%s,%s] of %s(%s)) {%s}", gen.LoopDecl(), key, val, iter, subj, body)
+}
+
+func (gen *Gen) GenAppend(expr *ast.CallExpr) string {
+    elem := ElemType(gen.Info.TypeOf(expr))
+    zero := gen.ZeroFunc(elem)
+    slice := gen.GenExpr(expr.Args[0])
+    if expr.Ellipsis.IsValid() {
+        // append(s, t...)
+        src := gen.GenExpr(expr.Args[1])
+        if gen.IsString(expr.Args[1]) {
+            src = "$stringToBytes(" + src + ")"
+        }
+        return "$appendSlice(" + slice + "," + src + "," + zero + ")"
+    }
+    return "$append(" + slice + ",[" + gen.GenValues(expr.Args[1:], RepeatType(elem, len(expr.Args) - 1)) + "]," + zero + ")"
+}
+
+func (gen *Gen) GenMakeSlice(expr *ast.CallExpr) string {
+    elem := ElemType(gen.Info.TypeOf(expr.Args[0]))
+    var out string
+    out += "$makeSlice("
+    out += gen.GenExpr(expr.Args[1])
+    out += ","
+    if len(expr.Args) > 2 {
+        out += gen.GenExpr(expr.Args[2])
+    } else {
+        out += "undefined"
+    }
+    out += "," + gen.ZeroFunc(elem) + ")"
+    return out
+}
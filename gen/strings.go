@@ -0,0 +1,102 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "strings"
+    "go/types"
+)
+
+// Strings are js strings of the bytes of their utf-8 encoding, see
+// runtime.js. Bindings receive and return js strings, the generated
+// code converts them.
+
+func IsString(t types.Type) bool {
+    basic := basicOf(t)
+    return basic != nil && basic.Info() & types.IsString != 0
+}
+
+func (gen *Gen) IsString(expr ast.Expr) bool {
+    return IsString(gen.Info.TypeOf(expr))
+}
+
+// JsString returns the js literal of the bytes of a go string.
+func JsString(s string) string {
+    var out strings.Builder
+    out.WriteByte('"')
+    for i := 0; i < len(s); i++ {
+        c := s[i]
+        switch {
+            case c == '"' || c == '\\': out.WriteString("\\" + string(c))
+            case c >= 0x20 && c < 0x7f: out.WriteByte(c)
+            default: fmt.Fprintf(&out, "\\x%02x", c)
+        }
+    }
+    out.WriteByte('"')
+    return out.String()
+}
+
+// elemKind returns the kind of the elements of a slice type, it is
+// types.Invalid for other types.
+func elemKind(t types.Type) types.BasicKind {
+    slice, ok := t.Underlying().(*types.Slice)
+    if !ok {
+        return types.Invalid
+    }
+    if basic := basicOf(slice.Elem()); basic != nil {
+        return basic.Kind()
+    }
+    return types.Invalid
+}
+
+// IsStringConversion reports if converting from to to changes the
+// representation of a string, bytes or runes.
+func IsStringConversion(from types.Type, to types.Type) bool {
+    if IsString(to) {
+        return !IsString(from)
+    }
+    kind := elemKind(to)
+    return IsString(from) && (kind == types.Uint8 || kind == types.Int32)
+}
+
+// GenStringConversion generates `string(x)`, `[]byte(s)` and
+// `[]rune(s)`.
+func (gen *Gen) GenStringConversion(arg ast.Expr, to types.Type) string {
+    from := gen.Info.TypeOf(arg)
+    x := gen.GenExpr(arg)
+    if IsString(to) {
+        switch elemKind(from) {
+            case types.Uint8: return "$bytesToString(" + x + ")"
+            case types.Int32: return "$runesToString(" + x + ")"
+            default: return "$runeToString(" + x + ")"
+        }
+    }
+    if elemKind(to) == types.Uint8 {
+        return "$stringToBytes(" + x + ")"
+    }
+    return "$stringToRunes(" + x + ")"
+}
+
+func (gen *Gen) GenStringIndex(expr *ast.IndexExpr) string {
+    gen.AddDepth()
+    out := "$stringGet(" + gen.GenExpr(expr.X) + "," + gen.GenExpr(expr.Index) + ")"
+    gen.RemDepth()
+    return out
+}
+
+func (gen *Gen) GenStringSlice(expr *ast.SliceExpr) string {
+    gen.AddDepth()
+    var out string
+    out += "$stringSlice(" + gen.GenExpr(expr.X)
+    for _, index := range []ast.Expr{expr.Low, expr.High} {
+        out += ","
+        if index != nil {
+            out += gen.GenExpr(index)
+        } else {
+            out += "undefined"
+        }
+    }
+    out += ")"
+    gen.RemDepth()
+    return out
+}
@@ -0,0 +1,257 @@
+package gen
+
+import (
+    "fmt"
+    "go/ast"
+    "go/token"
+    "go/types"
+)
+
+// TypeName returns the js name of the constructor of a named type.
+func (gen *Gen) TypeName(t *types.Named) string {
+    obj := t.Obj()
+    if obj.Pkg() == nil || gen.IsLocal(obj) {
+        return JsName(obj.Name())
+    }
+    return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// IsLocal reports if the object is declared on the package being
+// generated.
+func (gen *Gen) IsLocal(obj types.Object) bool {
+    return obj.Pkg() != nil && obj.Pkg().Path() == "src"
+}
+
+// IsJsType reports if the type is bound to a js value, see the
+// `js-bind` types of the library. Values of those types are opaque
+// references that are never copied.
+func (gen *Gen) IsJsType(t types.Type) bool {
+    return gen.JsTypeDoc(t) != nil
+}
+
+// JsTypeDoc returns the `js-bind` comment of a type, the lines after
+// `js-bind` are the js expression of its zero value.
+func (gen *Gen) JsTypeDoc(t types.Type) *ast.CommentGroup {
+    named, ok := t.(*types.Named)
+    if !ok {
+        return nil
+    }
+    if gen.jsTypes == nil {
+        gen.FindJsTypes()
+    }
+    return gen.jsTypes[named.Obj()]
+}
+
+// FindJsTypes finds the `js-bind` comments of the types declared by
+// the packages, the types are looked up by JsTypeDoc.
+func (gen *Gen) FindJsTypes() {
+    gen.jsTypes = map[types.Object]*ast.CommentGroup{}
+    for _, decl := range gen.Decls() {
+        e, ok := decl.(*ast.GenDecl)
+        if !ok || e.Tok != token.TYPE {
+            continue
+        }
+        for _, spec := range e.Specs {
+            t := spec.(*ast.TypeSpec)
+            doc := t.Doc
+            if doc == nil && len(e.Specs) == 1 {
+                doc = e.Doc
+            }
+            if doc != nil && doc.List[0].Text[2:] == "js-bind" {
+                gen.jsTypes[gen.Info.Defs[t.Name]] = doc
+            }
+        }
+    }
+}
+
+// ZeroValue returns a js expression that evaluates to a new zero
+// value of the type.
+func (gen *Gen) ZeroValue(t types.Type) string {
+    if tp, ok := t.(*types.TypeParam); ok {
+        return TypeParamDesc(tp) + ".zero()"
+    }
+    if doc := gen.JsTypeDoc(t); doc != nil {
+        var out string
+        for _, line := range doc.List[1:] {
+            out += line.Text[2:]
+        }
+        if out == "" {
+            return "null"
+        }
+        return out
+    }
+    switch u := t.Underlying().(type) {
+        case *types.Basic: {
+            info := u.Info()
+            switch {
+                case info & types.IsBoolean != 0: return "false"
+                case info & types.IsString != 0: return "\"\""
+                case IsBigInt(t): return "0n"
+                case info & types.IsNumeric != 0: return "0"
+                default: return "null"
+            }
+        }
+        case *types.Struct: {
+            var fields string
+            named, isNamed := t.(*types.Named)
+            for i := 0; i < u.NumFields(); i++ {
+                if !isNamed {
+                    fields += u.Field(i).Name() + ":"
+                }
+                fields += gen.ZeroValue(u.Field(i).Type())
+                if i < u.NumFields() - 1 {
+                    fields += ","
+                }
+            }
+            if isNamed {
+                return "new " + gen.TypeName(named) + "(" + fields + ")"
+            }
+            return "{" + fields + "}"
+        }
+        case *types.Array: {
+            return fmt.Sprintf("Array.from({length:%d},()=>(%s))", u.Len(), gen.ZeroValue(u.Elem()))
+        }
+        default: return "null"
+    }
+}
+
+// MapHash returns the js function used by a map to hash its keys, keys
+// that js already compares like go does not need a hash function.
+func (gen *Gen) MapHash(key types.Type) string {
+    if tp, ok := key.(*types.TypeParam); ok {
+        return TypeParamDesc(tp) + ".mapHash"
+    }
+    switch key.Underlying().(type) {
+        case *types.Struct, *types.Array, *types.Interface: {
+            return "(k)=>JSON.stringify(" + gen.HashValue("k", key) + ")"
+        }
+        default: return "null"
+    }
+}
+
+// HashValue returns a js expression that converts the value of expr
+// into something JSON.stringify can encode, values that are equal in
+// go are encoded to the same string.
+func (gen *Gen) HashValue(expr string, t types.Type) string {
+    if tp, ok := t.(*types.TypeParam); ok {
+        return TypeParamDesc(tp) + ".hash(" + expr + ")"
+    }
+    if gen.IsJsType(t) {
+        return "$id(" + expr + ")"
+    }
+    switch u := t.Underlying().(type) {
+        case *types.Basic: {
+            if IsBigInt(t) {
+                // JSON.stringify cannot encode a BigInt
+                return "String(" + expr + ")"
+            }
+            return expr
+        }
+        case *types.Interface: return "$ifaceHash(" + expr + ")"
+        case *types.Struct: {
+            var out string
+            out += "["
+            for i := 0; i < u.NumFields(); i++ {
+                field := u.Field(i)
+                out += gen.HashValue(expr + "." + field.Name(), field.Type())
+                if i < u.NumFields() - 1 {
+                    out += ","
+                }
+            }
+            out += "]"
+            return out
+        }
+        case *types.Array: {
+            return expr + ".map(($e)=>" + gen.HashValue("$e", u.Elem()) + ")"
+        }
+        default: return "$id(" + expr + ")"
+    }
+}
+
+// CloneValue returns a js expression that copies the value of expr
+// when values of the type are copied on assignment in go.
+func (gen *Gen) CloneValue(expr string, t types.Type) string {
+    if !gen.NeedsClone(t) {
+        return expr
+    }
+    if tp, ok := t.(*types.TypeParam); ok {
+        return TypeParamDesc(tp) + ".clone(" + expr + ")"
+    }
+    switch u := t.Underlying().(type) {
+        case *types.Array: {
+            if !gen.NeedsClone(u.Elem()) {
+                return expr + ".slice()"
+            }
+            return expr + ".map(($e)=>" + gen.CloneValue("$e", u.Elem()) + ")"
+        }
+        case *types.Struct: {
+            if _, ok := t.(*types.Named); ok {
+                return expr + ".$clone(" + gen.GenTypeArgs(NamedTypeArgs(t)) + ")"
+            }
+            // anonymous structs are js objects
+            var fields string
+            for i := 0; i < u.NumFields(); i++ {
+                field := u.Field(i)
+                fields += field.Name() + ":" + gen.CloneValue("$s." + field.Name(), field.Type())
+                if i < u.NumFields() - 1 {
+                    fields += ","
+                }
+            }
+            return "(($s)=>({" + fields + "}))(" + expr + ")"
+        }
+        default: return expr
+    }
+}
+
+// NeedsClone reports if values of the type are not shared on
+// assignment.
+func (gen *Gen) NeedsClone(t types.Type) bool {
+    if gen.IsJsType(t) {
+        return false
+    }
+    if IsTypeParam(t) {
+        // the type argument can be an array or a struct
+        return true
+    }
+    switch t.Underlying().(type) {
+        case *types.Array, *types.Struct: return true
+        default: return false
+    }
+}
+
+// EqualValue returns a js expression comparing the values of the js
+// expressions a and b with the go `==` operator.
+func (gen *Gen) EqualValue(a string, b string, t types.Type) string {
+    if tp, ok := t.(*types.TypeParam); ok {
+        return TypeParamDesc(tp) + ".equal(" + a + "," + b + ")"
+    }
+    if gen.IsJsType(t) {
+        return a + "===" + b
+    }
+    switch u := t.Underlying().(type) {
+        case *types.Struct: {
+            var out string
+            for i := 0; i < u.NumFields(); i++ {
+                field := u.Field(i)
+                if field.Name() == "_" {
+                    continue
+                }
+                if out != "" {
+                    out += "&&"
+                }
+                out += gen.EqualValue(a + "." + field.Name(), b + "." + field.Name(), field.Type())
+            }
+            if out == "" {
+                // no fields or only blank ones
+                return "true"
+            }
+            return "(" + out + ")"
+        }
+        case *types.Array: {
+            e, i := gen.Temp("e"), gen.Temp("i")
+            return a + ".every((" + e + "," + i + ")=>" + gen.EqualValue(e, b + "[" + i + "]", u.Elem()) + ")"
+        }
+        case *types.Interface: return "$ifaceEq(" + a + "," + b + ")"
+        default: return a + "===" + b
+    }
+}
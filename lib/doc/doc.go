@@ -1,5 +1,6 @@
 package doc
 
+//js-bind
 type HTMLElement struct {}
 
 //js-bind
@@ -7,7 +8,7 @@ type HTMLElement struct {}
 func QuerySelector(query string) HTMLElement {}
 
 //js-bind
-//document.querySelectorAll(%args%)
+//$sliceOf(Array.from(document.querySelectorAll(%args%)))
 func QuerySelectorAll(query string) []HTMLElement {}
 
 //js-bind
@@ -29,3 +30,7 @@ func (HTMLElement) AppendChild(elem HTMLElement) {}
 //js-bind
 //%recv%.play(%args%)
 func (HTMLElement) Play() {}
+
+//js-bind
+//%recv%.addEventListener(%args%)
+func (HTMLElement) AddEventListener(event string, listener func()) {}
@@ -0,0 +1,29 @@
+package sync
+
+//js-bind
+//new $Mutex()
+type Mutex struct {}
+
+//js-bind
+//await %recv%.lock()
+func (m *Mutex) Lock() {}
+
+//js-bind
+//%recv%.unlock()
+func (m *Mutex) Unlock() {}
+
+//js-bind
+//new $WaitGroup()
+type WaitGroup struct {}
+
+//js-bind
+//%recv%.add(%args%)
+func (wg *WaitGroup) Add(delta int) {}
+
+//js-bind
+//%recv%.add(-1)
+func (wg *WaitGroup) Done() {}
+
+//js-bind
+//await %recv%.wait()
+func (wg *WaitGroup) Wait() {}
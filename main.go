@@ -3,7 +3,9 @@ package main
 import (
     "os"
     "fmt"
+    "flag"
     "errors"
+    "go/ast"
     "go/types"
     "go/token"
     "elma/gen"
@@ -13,44 +15,73 @@ import (
 type ElmaImporter struct {
     Root string
     Pkgs []*packages.Package
+    // Fset is the file set used to load Pkgs.
+    Fset *token.FileSet
+    // Info is shared between every checked package so the generator
+    // can resolve objects that come from imported packages.
+    Info *types.Info
+    // Cache holds the already checked packages, checking a package
+    // twice would create different objects for the same declarations.
+    Cache map[string]*types.Package
+    // Errors collects the type errors of every checked package.
+    Errors []error
 }
 
-const MAX_FILE_SIZE = 1024 * 8
+func (imp *ElmaImporter) AddError(err error) {
+    imp.Errors = append(imp.Errors, err)
+}
 
-func resolvePackage(pkg *packages.Package, imp ElmaImporter) (*types.Package, error) {
-    fset := token.NewFileSet()
+func resolvePackage(pkg *packages.Package, imp *ElmaImporter) *types.Package {
     tcfg := types.Config{
         Importer: imp,
         IgnoreFuncBodies: true,
+        Error: imp.AddError,
     }
 
-    for _, file := range pkg.GoFiles {
-        fset.AddFile(file, fset.Base(), MAX_FILE_SIZE)
-    }
-
-    return tcfg.Check(pkg.ID, fset, pkg.Syntax, nil)
+    // the errors are collected by AddError
+    checked, _ := tcfg.Check(pkg.ID, imp.Fset, pkg.Syntax, imp.Info)
+    return checked
 }
 
-func (imp ElmaImporter) Import(path string) (*types.Package, error) {
+func (imp *ElmaImporter) Import(path string) (*types.Package, error) {
+    if pkg, ok := imp.Cache[path]; ok {
+        return pkg, nil
+    }
     for _, pkg := range imp.Pkgs {
         if pkg.ID == imp.Root + "/" + path {
-            return resolvePackage(pkg, imp)
+            checked := resolvePackage(pkg, imp)
+            imp.Cache[path] = checked
+            return checked, nil
         }
     }
     return nil, errors.New("package not found")
 }
 
+func reportErrors(errs []error) {
+    for _, err := range errs {
+        fmt.Fprintf(os.Stderr, "%v\n", err)
+    }
+    if len(errs) > 0 {
+        os.Exit(1)
+    }
+}
+
 func main() {
-    if len(os.Args) < 2 {
+    sharedLoopVars := flag.Bool("shared-loopvars", false, "share the variables of a loop between its iterations like before go 1.22")
+    flag.Parse()
+
+    if flag.NArg() < 1 {
         fmt.Fprintf(os.Stderr, "ERROR: missing path argument\n")
         os.Exit(1)
     }
 
+    fset := token.NewFileSet()
     cfg := &packages.Config {
         Mode: packages.NeedFiles | packages.NeedSyntax,
+        Fset: fset,
     }
 
-    path := os.Args[1]
+    path := flag.Arg(0)
     path_pattern := path + "/..."
 
     src, err := packages.Load(cfg, path_pattern)
@@ -59,6 +90,11 @@ func main() {
         os.Exit(1)
     }
 
+    if len(src) == 0 {
+        fmt.Fprintf(os.Stderr, "ERROR: no package found at %s\n", path)
+        os.Exit(1)
+    }
+
     lib, err := packages.Load(cfg, "./lib/...")
     if err != nil {
         fmt.Fprintf(os.Stderr, "%v\n", err)
@@ -70,26 +106,57 @@ func main() {
     for _, pkg := range lib { all = append(all, pkg) }
     for _, pkg := range src { all = append(all, pkg) }
 
-    fset := token.NewFileSet()
-    tcfg := types.Config{
-        Importer: ElmaImporter{"elma", all},
+    load_errs := []error{}
+    for _, pkg := range all {
+        for _, err := range pkg.Errors {
+            load_errs = append(load_errs, err)
+        }
+    }
+    reportErrors(load_errs)
+
+    info := &types.Info{
+        Types: map[ast.Expr]types.TypeAndValue{},
+        Defs: map[*ast.Ident]types.Object{},
+        Uses: map[*ast.Ident]types.Object{},
+        Implicits: map[ast.Node]types.Object{},
+        Selections: map[*ast.SelectorExpr]*types.Selection{},
+        Scopes: map[ast.Node]*types.Scope{},
+        Instances: map[*ast.Ident]types.Instance{},
+        // each check replaces it, the last one is the src package
+        InitOrder: []*types.Initializer{},
     }
 
-    fset.AddFile(path + "/main.go", fset.Base(), MAX_FILE_SIZE)
-
-    _, e := tcfg.Check("src", fset, src[0].Syntax, nil)
-    if e != nil {
-        fmt.Fprintf(os.Stderr, "%v\n", e)
-        os.Exit(1)
+    imp := &ElmaImporter{
+        Root: "elma",
+        Pkgs: all,
+        Fset: fset,
+        Info: info,
+        Cache: map[string]*types.Package{},
+    }
+    tcfg := types.Config{
+        Importer: imp,
+        Error: imp.AddError,
     }
 
+    tcfg.Check("src", fset, src[0].Syntax, info)
+    reportErrors(imp.Errors)
+
     g := gen.Gen{
         Pkgs: all,
-        Binds: map[string]string{},
+        Fset: fset,
+        Info: info,
+        Binds: map[types.Object]string{},
+        SharedLoopVars: *sharedLoopVars,
     }
 
     out := g.GenPkg(src[0])
 
+    gen_errs := []error{}
+    for _, err := range g.Errors {
+        gen_errs = append(gen_errs, err)
+    }
+    reportErrors(gen_errs)
+
     os.Remove(path + "/main.js")
 
     outfile, err := os.OpenFile(path + "/main.js", os.O_RDWR|os.O_CREATE, 0644)
@@ -98,6 +165,7 @@ func main() {
         os.Exit(1)
     }
 
+    outfile.WriteString(gen.Runtime)
     outfile.WriteString(out)
     outfile.Close()
 }
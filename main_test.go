@@ -0,0 +1,43 @@
+package main
+
+import (
+    "os"
+    "strings"
+    "os/exec"
+    "testing"
+    "path/filepath"
+)
+
+// TestPrograms generates every program of testdata and runs it with
+// node, the output must be the want.txt file of the program.
+func TestPrograms(t *testing.T) {
+    if _, err := exec.LookPath("node"); err != nil {
+        t.Skip("node is not installed")
+    }
+    dirs, err := filepath.Glob("testdata/*")
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, dir := range dirs {
+        dir := dir
+        t.Run(filepath.Base(dir), func(t *testing.T) {
+            gen := exec.Command("go", "run", ".", "./" + dir)
+            if out, err := gen.CombinedOutput(); err != nil {
+                t.Fatalf("generating %s: %v\n%s", dir, err, out)
+            }
+            defer os.Remove(filepath.Join(dir, "main.js"))
+
+            out, err := exec.Command("node", filepath.Join(dir, "main.js")).CombinedOutput()
+            if err != nil {
+                t.Fatalf("running %s: %v\n%s", dir, err, out)
+            }
+            want, err := os.ReadFile(filepath.Join(dir, "want.txt"))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if strings.TrimSpace(string(out)) != strings.TrimSpace(string(want)) {
+                t.Errorf("output of %s:\n%s\nwant:\n%s", dir, out, want)
+            }
+        })
+    }
+}
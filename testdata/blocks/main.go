@@ -0,0 +1,28 @@
+package main
+
+import (
+	"lib/fmt"
+	"lib/sync"
+)
+
+func get(ch chan int) int { return <-ch + len("x") }
+
+func main() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	ch := make(chan int, 1)
+	go func() {
+		ch <- 1
+		wg.Done()
+	}()
+	wg.Wait()
+	for _, x := range []int{1} {
+		fmt.Println("range", x)
+	}
+	fmt.Println("get", get(ch))
+
+	done := make(chan bool)
+	go func() { done <- true }()
+	<-done
+	fmt.Println("done")
+}
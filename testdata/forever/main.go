@@ -0,0 +1,24 @@
+package main
+
+import "lib/fmt"
+
+func main() {
+	ch := make(chan int)
+	quit := make(chan bool)
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- i
+		}
+		quit <- true
+	}()
+	sum := 0
+	for {
+		select {
+		case v := <-ch:
+			sum += v
+		case <-quit:
+			fmt.Println("sum", sum)
+			return
+		}
+	}
+}
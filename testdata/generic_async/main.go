@@ -0,0 +1,27 @@
+package main
+
+import "lib/fmt"
+
+func Map[T, U any](xs []T, f func(T) U) []U {
+	out := make([]U, 0, len(xs))
+	for _, x := range xs {
+		out = append(out, f(x))
+	}
+	return out
+}
+
+type Worker struct {
+	results chan int
+}
+
+func (w *Worker) Work(x int) int {
+	go func() { w.results <- x * 10 }()
+	return <-w.results
+}
+
+func main() {
+	w := &Worker{results: make(chan int)}
+	xs := Map([]int{1, 2, 3}, w.Work)
+	fmt.Println(xs[0], xs[1], xs[2])
+	fmt.Println(Map([]int{4}, func(x int) bool { return x > 3 })[0])
+}
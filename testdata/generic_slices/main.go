@@ -0,0 +1,54 @@
+package main
+
+import "lib/fmt"
+
+type Ints []int
+
+func Concat[S ~[]E, E any](slices ...S) S {
+	var out S
+	for _, s := range slices {
+		out = append(out, s...)
+	}
+	return out
+}
+
+func Repeat[S ~[]E, E any](x E, n int) S {
+	s := make(S, n)
+	for i := range s {
+		s[i] = x
+	}
+	return s
+}
+
+func Count[S ~[]E, E any](s S) int {
+	return len(s)
+}
+
+func Keys[M ~map[K]V, K comparable, V any](m M) int {
+	n := 0
+	for range m {
+		n++
+	}
+	return n + len(m)
+}
+
+func Fill[C ~chan E, E any](x E, n int) C {
+	ch := make(C, n)
+	for i := 0; i < n; i++ {
+		ch <- x
+	}
+	return ch
+}
+
+func main() {
+	s := Concat(Ints{1, 2}, Ints{3}, nil, Ints{4, 5})
+	fmt.Println(s[0], s[2], s[4], len(s), Count(s))
+	r := Repeat[[]string]("go", 3)
+	fmt.Println(r[0], r[2], Count(r))
+	z := Repeat[[][2]int]([2]int{}, 2)
+	z[0][1] = 7
+	fmt.Println(z[0][0], z[0][1], z[1][1])
+	fmt.Println(Keys(map[string]bool{"a": true, "b": false}))
+	ch := Fill[chan int](9, 2)
+	fmt.Println(len(ch), <-ch, len(ch))
+}
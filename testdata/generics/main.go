@@ -0,0 +1,51 @@
+package main
+
+import "lib/fmt"
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+func Sum[T Number](xs []T) T {
+	var s T
+	for _, x := range xs {
+		s += x
+	}
+	return s
+}
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(x T) { s.items = append(s.items, x) }
+
+func (s *Stack[T]) Pop() (T, bool) {
+	var zero T
+	if len(s.items) == 0 {
+		return zero, false
+	}
+	x := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return x, true
+}
+
+func (s *Stack[T]) Wait(ch chan T) T { return <-ch }
+
+func Zero[T any]() T {
+	var z T
+	return z
+}
+
+func main() {
+	fmt.Println(Sum([]int{1, 2, 3}), Sum([]float64{1.5, 2}))
+	s := &Stack[string]{}
+	s.Push("a")
+	s.Push("b")
+	v, ok := s.Pop()
+	fmt.Println(v, ok)
+	ch := make(chan string, 1)
+	ch <- "c"
+	fmt.Println(s.Wait(ch))
+	fmt.Println(Zero[struct{ X int }]().X)
+}
@@ -0,0 +1,35 @@
+package main
+
+import "lib/fmt"
+
+var (
+	a = b + 1
+	b = f("b")
+	c, d = pair()
+	_ = f("blank")
+)
+
+var order = ""
+
+func f(name string) int {
+	order += name + " "
+	return 10
+}
+
+func pair() (int, int) {
+	order += "pair "
+	return a, b
+}
+
+func init() {
+	fmt.Println("init 1", a, b, c, d)
+}
+
+func init() {
+	order += "init2 "
+	fmt.Println("init 2")
+}
+
+func main() {
+	fmt.Println("main", order)
+}
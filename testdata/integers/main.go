@@ -0,0 +1,45 @@
+package main
+
+import "lib/fmt"
+
+func main() {
+	a, b := 7, 2
+	fmt.Println(a/b, -a/b, a%b, -a%b, a%-b)
+
+	var i8 int8 = 127
+	i8++
+	fmt.Println(i8)
+
+	var u8 uint8 = 250
+	u8 += 10
+	fmt.Println(u8, u8<<4, u8>>1)
+
+	var u16 uint16 = 0
+	u16--
+	fmt.Println(u16)
+
+	var i32 int32 = 1 << 30
+	i32 *= 4
+	fmt.Println(i32)
+
+	var u32 uint32 = 1
+	u32 = u32<<31 | 1
+	fmt.Println(u32, u32>>31, ^u32)
+
+	var i64 int64 = 1 << 62
+	i64 *= 2
+	fmt.Println(int(i64>>60), i64 < 0)
+	fmt.Println(int(int64(9007199254740993) % 10))
+
+	var u64 uint64 = 1 << 63
+	fmt.Println(int(u64>>60), int(u64/3%1000))
+
+	x := 0xF0
+	fmt.Println(x&0x3C, x|0x0F, x^0xFF, x&^0x30, -x>>2)
+
+	f := 7.0
+	fmt.Println(f/2, int(f/2), int(-f/2))
+
+	var n uint = 3
+	fmt.Println(1<<n, -8>>n)
+}
@@ -0,0 +1,55 @@
+package main
+
+import "lib/fmt"
+
+type Getter interface {
+	Get() int
+}
+
+type IntGetter struct{ v int }
+
+func (g IntGetter) Get() int { return g.v }
+
+type StrGetter struct{ v string }
+
+func (g StrGetter) Get() string { return g.v }
+
+type Pair[T any] struct{ v T }
+
+func (p Pair[T]) Get() T { return p.v }
+
+type Blank struct {
+	_ int
+	_ string
+}
+
+func check(x interface{}) {
+	g, ok := x.(Getter)
+	if ok {
+		fmt.Println("getter", g.Get())
+		return
+	}
+	fmt.Println("not a getter")
+}
+
+func main() {
+	check(IntGetter{1})
+	check(StrGetter{"a"})
+	check(Pair[int]{2})
+	check(Pair[string]{"b"})
+	switch x := interface{}(StrGetter{"c"}).(type) {
+	case Getter:
+		fmt.Println("getter", x.Get())
+	default:
+		fmt.Println("default")
+	}
+
+	defer func() {
+		_, ok := recover().(error)
+		fmt.Println("error", ok)
+	}()
+
+	fmt.Println(Blank{} == Blank{}, interface{}(Blank{}) == interface{}(Blank{}))
+	var xs []int
+	_ = xs[1]
+}
@@ -0,0 +1,76 @@
+package main
+
+import "lib/fmt"
+
+func find(grid [][]int, target int) (int, int) {
+	x, y := -1, -1
+outer:
+	for i, row := range grid {
+		for j, v := range row {
+			if v == target {
+				x, y = i, j
+				break outer
+			}
+		}
+	}
+	return x, y
+}
+
+func main() {
+	grid := [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	fmt.Println(find(grid, 5))
+	fmt.Println(find(grid, 10))
+
+	count := 0
+rows:
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if j > i {
+				continue rows
+			}
+			count++
+		}
+	}
+	fmt.Println(count)
+
+	ch := make(chan int, 1)
+	n := 0
+loop:
+	for {
+		select {
+		case v := <-ch:
+			n += v
+			if n > 2 {
+				break loop
+			}
+		default:
+			ch <- 1
+		}
+	}
+	fmt.Println("select", n)
+
+	m := 0
+	for i := 0; i < 5; i++ {
+		switch {
+		case i == 3:
+			break
+		default:
+			m += i
+		}
+	}
+	fmt.Println("switch", m)
+
+	i := 0
+again:
+	if i < 3 {
+		i++
+		goto again
+	}
+	fmt.Println("goto", i)
+
+	j := 0
+	goto skip
+skip:
+	j++
+	fmt.Println("skip", j)
+}
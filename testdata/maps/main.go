@@ -0,0 +1,60 @@
+package main
+
+import "lib/fmt"
+
+type Key struct {
+	X, Y int
+}
+
+func main() {
+	m := make(map[string]int)
+	m["a"] = 1
+	m["b"] += 2
+	m["a"]++
+	fmt.Println(m["a"], m["b"], m["missing"], len(m))
+
+	v, ok := m["b"]
+	fmt.Println(v, ok)
+	_, ok = m["c"]
+	fmt.Println(ok)
+
+	points := map[Key]string{{1, 2}: "p"}
+	points[Key{3, 4}] = "q"
+	fmt.Println(points[Key{1, 2}], points[Key{3, 4}], len(points))
+
+	floats := map[float64]bool{1: true}
+	fmt.Println(floats[1.0], floats[2])
+
+	structs := map[int]Key{}
+	k := structs[7]
+	fmt.Println(k.X, k.Y, len(structs))
+
+	delete(m, "a")
+	delete(m, "zzz")
+	fmt.Println(len(m), m["a"])
+
+	nums := map[int]int{}
+	for i := 0; i < 10; i++ {
+		nums[i] = i * i
+	}
+	for k := range nums {
+		if k%2 == 0 {
+			delete(nums, k)
+		}
+	}
+	sum := 0
+	for _, v := range nums {
+		sum += v
+	}
+	fmt.Println(len(nums), sum)
+
+	var empty map[string]int
+	fmt.Println(empty["x"], len(empty), empty == nil)
+
+	nested := map[string][]int{}
+	nested["a"] = append(nested["a"], 1, 2)
+	fmt.Println(len(nested["a"]), nested["a"][1])
+
+	ifaces := map[interface{}]int{1: 1, "1": 2}
+	fmt.Println(ifaces[1], ifaces["1"], ifaces[1.5])
+}
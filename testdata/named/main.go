@@ -0,0 +1,76 @@
+package main
+
+import "lib/fmt"
+
+type Mode int
+
+const (
+	Off Mode = iota
+	On
+)
+
+func (m Mode) String() string {
+	if m == On {
+		return "on"
+	}
+	return "off"
+}
+
+func (m *Mode) Toggle() {
+	*m = 1 - *m
+}
+
+type IDs []string
+
+func (ids IDs) Len() int { return len(ids) }
+
+type Counts map[string]int
+
+func (c Counts) Add(k string) { c[k]++ }
+
+type Handler func(int) int
+
+func (h Handler) Twice(x int) int { return h(h(x)) }
+
+type Celsius float64
+
+type Alias = Mode
+
+func main() {
+	m := Off
+	m.Toggle()
+	fmt.Println(m.String(), int(m))
+	var a Alias = On
+	fmt.Println(a.String())
+
+	ids := IDs{"a", "b"}
+	ids = append(ids, "c")
+	fmt.Println(ids.Len())
+
+	c := Counts{}
+	c.Add("x")
+	c.Add("x")
+	fmt.Println(c["x"])
+
+	h := Handler(func(x int) int { return x * 3 })
+	fmt.Println(h.Twice(2))
+
+	f := 3.9
+	fmt.Println(int(f), int(-f), int8(int(200+f)), uint8(int(-f)))
+	n := 300
+	fmt.Println(uint8(n), int8(n), float64(n)/7 > 42)
+
+	t := Celsius(36.6)
+	fmt.Println(float64(t) > 36)
+
+	bs := []byte("hi")
+	rs := []rune("hé")
+	fmt.Println(string(bs) == "hi", len(rs), string(rs) == "hé")
+
+	var s fmt_stringer = m
+	fmt.Println(s.String())
+}
+
+type fmt_stringer interface {
+	String() string
+}
@@ -0,0 +1,62 @@
+package main
+
+import "lib/fmt"
+
+type S struct {
+	Arr [2]int
+}
+
+type Point struct {
+	X, Y int
+}
+
+func (p Point) Get() int { return p.X + p.Y }
+
+type Outer struct {
+	*Point
+}
+
+type Grid [2][2]int
+
+func (g Grid) Sum() int { return g[0][0] + g[0][1] + g[1][0] + g[1][1] }
+
+func main() {
+	a := [2]int{1, 2}
+	p := &a[0]
+	a = [2]int{5, 6}
+	fmt.Println(*p, a[0])
+	*p = 9
+	fmt.Println(a[0])
+
+	var s S
+	q := &s.Arr[1]
+	s.Arr = [2]int{7, 8}
+	fmt.Println(*q)
+	s = S{Arr: [2]int{3, 4}}
+	fmt.Println(*q)
+
+	pa := &a
+	r := &a[1]
+	*pa = [2]int{10, 11}
+	fmt.Println(*r)
+
+	g := Grid{{1, 2}, {3, 4}}
+	row := &g[1]
+	g = Grid{{5, 6}, {7, 8}}
+	fmt.Println(row[0], row[1])
+
+	pt := &Point{1, 2}
+	fm := pt.Get
+	pt.X = 10
+	fmt.Println(fm(), pt.Get())
+
+	o := Outer{pt}
+	om := o.Get
+	pt.Y = 20
+	fmt.Println(om(), o.Get())
+
+	pg := &g
+	gm := pg.Sum
+	g[0][0] = 100
+	fmt.Println(gm(), pg.Sum())
+}
@@ -0,0 +1,73 @@
+package main
+
+import "lib/fmt"
+
+func seq(n int) func(func(int) bool) {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i * 10) {
+				return
+			}
+		}
+	}
+}
+
+func main() {
+	sum := 0
+	for i, v := range []int{5, 6, 7} {
+		sum += i * v
+	}
+	fmt.Println(sum)
+
+	arr := [3]int{1, 2, 3}
+	for i, v := range arr {
+		arr[2] = 100
+		if i == 2 {
+			fmt.Println("array copy", v)
+		}
+	}
+
+	for i := range "héllo" {
+		fmt.Println("index", i)
+	}
+	for _, r := range "hé" {
+		fmt.Println("rune", r)
+	}
+
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	fmt.Println(total)
+
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+	for v := range ch {
+		fmt.Println("chan", v)
+	}
+
+	for i := range 3 {
+		fmt.Println("int", i)
+	}
+
+	for v := range seq(5) {
+		if v == 30 {
+			break
+		}
+		fmt.Println("seq", v)
+	}
+
+	var funcs []func() int
+	for i := range 3 {
+		funcs = append(funcs, func() int { return i })
+	}
+	fmt.Println(funcs[0](), funcs[1](), funcs[2]())
+
+	for range []int{1, 2} {
+		fmt.Println("blank")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "lib/fmt"
+
+func a(ch chan int, done chan bool) {
+	defer func() { done <- true }()
+	defer func() {
+		<-ch
+		fmt.Println("A recovered", recover())
+	}()
+	panic("boom")
+}
+
+func b(ch chan int, done chan bool) {
+	defer func() {
+		ch <- 1
+		<-done
+	}()
+}
+
+func main() {
+	ch := make(chan int)
+	done := make(chan bool)
+	finished := make(chan bool)
+	go func() {
+		a(ch, done)
+		finished <- true
+	}()
+	go b(ch, done)
+	<-finished
+	fmt.Println("end")
+}
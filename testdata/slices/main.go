@@ -0,0 +1,59 @@
+package main
+
+import "lib/fmt"
+
+func main() {
+	s := make([]int, 3, 10)
+	fmt.Println(len(s), cap(s))
+
+	t := s[1:2]
+	t = append(t, 5)
+	fmt.Println(len(t), cap(t), s[2])
+
+	u := s[0:2:2]
+	u = append(u, 7)
+	u[0] = 9
+	fmt.Println(len(u), cap(u), s[0], s[2])
+
+	var nilSlice []string
+	nilSlice = append(nilSlice, "a", "b")
+	fmt.Println(len(nilSlice), nilSlice[1], nilSlice == nil)
+
+	dst := make([]int, 2)
+	n := copy(dst, []int{1, 2, 3})
+	fmt.Println(n, dst[0], dst[1])
+
+	b := make([]byte, 3)
+	n = copy(b, "hey")
+	fmt.Println(n, b[0], b[2])
+
+	overlap := []int{1, 2, 3, 4}
+	copy(overlap[1:], overlap)
+	fmt.Println(overlap[0], overlap[1], overlap[2], overlap[3])
+
+	a := [3]int{1, 2, 3}
+	c := a
+	c[0] = 10
+	fmt.Println(a[0], c[0], len(a))
+
+	sl := a[:]
+	sl[1] = 20
+	fmt.Println(a[1])
+
+	grid := [2][2]int{}
+	row := grid[0]
+	row[0] = 1
+	fmt.Println(grid[0][0], row[0])
+
+	more := append([]int{}, s...)
+	more = append(more, []int{4, 5}...)
+	fmt.Println(len(more), more[4])
+
+	bytes := append([]byte("ab"), "cd"...)
+	fmt.Println(len(bytes), bytes[3])
+
+	defer func() {
+		fmt.Println("recovered", recover() != nil)
+	}()
+	_ = s[5]
+}
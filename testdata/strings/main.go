@@ -0,0 +1,40 @@
+package main
+
+import "lib/fmt"
+
+func main() {
+	s := "héllo, 世界"
+	fmt.Println(len(s), s[1], s[2])
+
+	b := []byte(s)
+	fmt.Println(len(b), b[0])
+
+	r := []rune(s)
+	fmt.Println(len(r), r[1], r[7])
+
+	fmt.Println(string(r[7]) == "世", string(rune(233)) == "é")
+	fmt.Println(string(b[:2]) == "h\xc3", string(r[:2]) == "hé")
+
+	c := 'a'
+	fmt.Println(c, c+1, '\n', 'é')
+
+	raw := `a\nb`
+	fmt.Println(len(raw), raw[1])
+
+	esc := "tab\there\x41é"
+	fmt.Println(len(esc), esc[3], esc[8])
+
+	fmt.Println("abc" < "abd", "é" > "z", s[7:] == "世界")
+	fmt.Println(s[:5])
+
+	joined := ""
+	for i := 0; i < 3; i++ {
+		joined += string(rune('a' + i))
+	}
+	fmt.Println(joined, len(joined))
+
+	bad := string([]byte{0xff, 'x'})
+	for i, r := range bad {
+		fmt.Println(i, r)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import "lib/fmt"
+
+func classify(n int) string {
+	switch {
+	case n < 0:
+		return "negative"
+	case n == 0:
+		return "zero"
+	case n < 10:
+		return "small"
+	}
+	return "big"
+}
+
+func days(month int) int {
+	switch month {
+	case 2:
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	default:
+		return 31
+	}
+}
+
+func main() {
+	fmt.Println(classify(-1), classify(0), classify(5), classify(50))
+	fmt.Println(days(2), days(6), days(7))
+
+	switch x := 3; x {
+	case 1, 2:
+		fmt.Println("one or two")
+	case 3:
+		fmt.Println("three")
+		fallthrough
+	case 4:
+		fmt.Println("four")
+	case 5:
+		fmt.Println("five")
+	}
+
+	for i := 0; i < 3; i++ {
+		switch i {
+		case 1:
+			continue
+		}
+		fmt.Println("loop", i)
+	}
+
+	calls := 0
+	next := func() int {
+		calls++
+		return calls
+	}
+	switch next() {
+	case 1:
+		fmt.Println("first call", calls)
+	}
+
+	switch s := "b"; {
+	case s == "a":
+		fmt.Println("a")
+	case s == "b":
+		fmt.Println("b")
+	}
+
+	switch {
+	default:
+		fmt.Println("default first")
+	case false:
+		fmt.Println("never")
+	}
+}